@@ -27,6 +27,22 @@ func Benchmark_jsonlex_lexer_2000kB(b *testing.B) {
 	runLexer(b, "../testdata/2000kB.json")
 }
 
+func Benchmark_jsonlex_indexed_2kB(b *testing.B) {
+	runIndexed(b, "../testdata/2kB.json")
+}
+
+func Benchmark_jsonlex_indexed_20kB(b *testing.B) {
+	runIndexed(b, "../testdata/20kB.json")
+}
+
+func Benchmark_jsonlex_indexed_200kB(b *testing.B) {
+	runIndexed(b, "../testdata/200kB.json")
+}
+
+func Benchmark_jsonlex_indexed_2000kB(b *testing.B) {
+	runIndexed(b, "../testdata/2000kB.json")
+}
+
 func Benchmark_jsonlex_cursor_2kB(b *testing.B) {
 	runCursor(b, "../testdata/2kB.json")
 }
@@ -68,6 +84,28 @@ func runLexer(b *testing.B, file string) {
 	}
 }
 
+func runIndexed(b *testing.B, file string) {
+	b.ReportAllocs()
+
+	f, _ := os.Open(file)
+	defer func() { _ = f.Close() }()
+	buf, _ := ioutil.ReadAll(f)
+
+	lexer := NewLexer(
+		func(kind TokenKind, load []byte, pos uint) bool {
+			if kind == TokenERR {
+				b.Fatal(kind)
+			}
+			return true
+		},
+	)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		lexer.ScanIndexed(buf)
+	}
+}
+
 func runCursor(b *testing.B, file string) {
 	b.ReportAllocs()
 
@@ -0,0 +1,337 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package cbor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/codec"
+)
+
+type (
+	// Tokenizer reads CBOR data items and yields the tokens of the
+	// equivalent JSON text, so that code consuming the token stream of
+	// the jsonlex.Lexer works on CBOR input unchanged. Text strings are
+	// yielded escaped like the string loads of the Lexer, byte strings
+	// base64 encoded. Integers, bignums and floats are yielded as
+	// jsonlex.TokenNUM, undefined as null. Other tags are skipped. Map
+	// keys must be strings. A sequence of data items results in a
+	// stream of concatenated values.
+	Tokenizer struct {
+		yield jsonlex.Yield
+		r     io.Reader
+		stack []frame
+		off   uint // number of bytes consumed
+		err   error
+		hold  bool // one[0] has been unread
+		one   [1]byte
+		num   [8]byte
+		load  []byte
+		str   []byte
+	}
+
+	// frame is an open map or array.
+	frame struct {
+		obj  bool
+		inf  bool   // indefinite length
+		left uint64 // number of keys, values or elements left
+		n    uint64 // number of keys, values or elements read
+		sep  bool   // separator yielded after the n-th item
+	}
+)
+
+// NewTokenizer creates a Tokenizer, the yield function
+// will be invoked for each token consumed by Scan().
+func NewTokenizer(yield jsonlex.Yield) *Tokenizer {
+	return &Tokenizer{yield: yield}
+}
+
+// NewCursor creates a jsonlex.Cursor over CBOR input.
+func NewCursor(r io.Reader, f jsonlex.Filter) *jsonlex.Cursor {
+	return jsonlex.NewCursor(r, f, jsonlex.CursorOptTokenizer(
+		func(yield jsonlex.Yield) jsonlex.Tokenizer { return NewTokenizer(yield) },
+	))
+}
+
+// Scan reads data items from r and invokes the yield function for each
+// token, until the yield function returns false or a jsonlex.TokenEOF
+// or jsonlex.TokenERR has been yielded. Scan() is reentrant, subsequent
+// invocations continue with the next token. Like the Lexer, the Tokenizer
+// reads byte by byte, wrap your reader with bufio.Reader or use bytes.Reader.
+func (t *Tokenizer) Scan(r io.Reader) {
+	t.r = r
+	for {
+		kind, load, pos := t.next()
+		if !t.yield(kind, load, pos) || kind == jsonlex.TokenEOF || kind == jsonlex.TokenERR {
+			return
+		}
+	}
+}
+
+// Err returns the error which caused the jsonlex.TokenERR, nil
+// otherwise. The error is either a *jsonlex.SyntaxError or an
+// error reported by the io.Reader. Errors are sticky.
+func (t *Tokenizer) Err() error {
+	return t.err
+}
+
+// Offset returns the number of bytes consumed.
+func (t *Tokenizer) Offset() uint {
+	return t.off
+}
+
+func (t *Tokenizer) next() (jsonlex.TokenKind, []byte, uint) {
+	if t.err != nil {
+		return jsonlex.TokenERR, []byte(t.err.Error()), t.off
+	}
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		if f.inf {
+			b, err := t.readByte()
+			if err != nil {
+				return t.fail(err)
+			}
+			if b == 0xFF {
+				return t.close(t.off - 1)
+			}
+			t.unread()
+		} else if f.left == 0 {
+			return t.close(t.off)
+		}
+		if f.n > 0 && !f.sep {
+			f.sep = true
+			if f.obj && f.n%2 == 1 {
+				return jsonlex.TokenCOL, append(t.load[:0], ':'), t.off
+			}
+			return jsonlex.TokenCOM, append(t.load[:0], ','), t.off
+		}
+	}
+	return t.item()
+}
+
+func (t *Tokenizer) item() (jsonlex.TokenKind, []byte, uint) {
+	pos, key := t.off, false
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		key = f.obj && f.n%2 == 0
+		f.n, f.sep = f.n+1, false
+		if !f.inf {
+			f.left--
+		}
+	}
+
+	var tag uint64
+	tagged := false
+
+	for {
+		b, err := t.readByte()
+		if err == io.EOF && len(t.stack) == 0 && !tagged {
+			return jsonlex.TokenEOF, nil, pos
+		}
+		if err != nil {
+			return t.fail(err)
+		}
+		major, info := b>>5, b&0x1F
+
+		arg, inf, err := t.arg(info)
+		if err != nil {
+			return t.fail(err)
+		}
+		if inf && (major < 2 || major == 6 || major == 7) {
+			return t.syntax(t.off-1, "unexpected byte 0x%02x", b)
+		}
+		if key && major != 2 && major != 3 && major != 6 {
+			return t.syntax(pos, "unsupported map key")
+		}
+
+		switch major {
+		case 0:
+			return jsonlex.TokenNUM, strconv.AppendUint(t.load[:0], arg, 10), pos
+		case 1:
+			if arg == math.MaxUint64 {
+				return jsonlex.TokenNUM, append(t.load[:0], "-18446744073709551616"...), pos
+			}
+			return jsonlex.TokenNUM, strconv.AppendUint(append(t.load[:0], '-'), arg+1, 10), pos
+		case 2, 3:
+			s, err := t.readString(major, arg, inf)
+			if err != nil {
+				return t.fail(err)
+			}
+			if major == 3 {
+				if !utf8.Valid(s) {
+					return t.syntax(pos, "invalid UTF-8 in text string")
+				}
+				t.load = jsonlex.Escape(t.load[:0], string(s))
+				return jsonlex.TokenSTR, t.load, pos
+			}
+			if tagged && (tag == 2 || tag == 3) && !key {
+				n := new(big.Int).SetBytes(s)
+				if tag == 3 {
+					n.Not(n)
+				}
+				return jsonlex.TokenNUM, n.Append(t.load[:0], 10), pos
+			}
+			t.load = append(t.load[:0], base64.StdEncoding.EncodeToString(s)...)
+			return jsonlex.TokenSTR, t.load, pos
+		case 4:
+			t.stack = append(t.stack, frame{inf: inf, left: arg})
+			return jsonlex.TokenLSB, append(t.load[:0], '['), pos
+		case 5:
+			if arg > math.MaxUint64/2 {
+				return t.syntax(pos, "map size %d out of range", arg)
+			}
+			t.stack = append(t.stack, frame{obj: true, inf: inf, left: 2 * arg})
+			return jsonlex.TokenLCB, append(t.load[:0], '{'), pos
+		case 6:
+			tag, tagged = arg, true
+			continue
+		}
+
+		switch info {
+		case 20:
+			return jsonlex.TokenLIT, append(t.load[:0], "false"...), pos
+		case 21:
+			return jsonlex.TokenLIT, append(t.load[:0], "true"...), pos
+		case 22, 23:
+			return jsonlex.TokenLIT, append(t.load[:0], "null"...), pos
+		case 25:
+			return t.float(float64(halfToFloat(uint16(arg))), pos)
+		case 26:
+			return t.float(float64(math.Float32frombits(uint32(arg))), pos)
+		case 27:
+			return t.float(math.Float64frombits(arg), pos)
+		}
+		return t.syntax(pos, "unsupported simple value %d", arg)
+	}
+}
+
+// close pops the innermost map or array.
+func (t *Tokenizer) close(pos uint) (jsonlex.TokenKind, []byte, uint) {
+	f := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	if !f.obj {
+		return jsonlex.TokenRSB, append(t.load[:0], ']'), pos
+	}
+	if f.n%2 == 1 {
+		return t.syntax(pos, "missing map value")
+	}
+	return jsonlex.TokenRCB, append(t.load[:0], '}'), pos
+}
+
+func (t *Tokenizer) float(f float64, pos uint) (jsonlex.TokenKind, []byte, uint) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return t.syntax(pos, "unsupported value %v", f)
+	}
+	t.load = codec.AppendFloat(t.load[:0], f)
+	return jsonlex.TokenNUM, t.load, pos
+}
+
+// arg reads the argument of a data item head.
+func (t *Tokenizer) arg(info byte) (arg uint64, inf bool, err error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info < 28:
+		n := 1 << (info - 24)
+		if _, err = io.ReadFull(t.r, t.num[:n]); err != nil {
+			return 0, false, err
+		}
+		t.off += uint(n)
+		for _, b := range t.num[:n] {
+			arg = arg<<8 | uint64(b)
+		}
+		return arg, false, nil
+	case info == 31:
+		return 0, true, nil
+	}
+	return 0, false, &jsonlex.SyntaxError{Msg: fmt.Sprintf("malformed argument %d", info), Pos: t.off - 1}
+}
+
+// readString reads the content of a definite or
+// indefinite length byte or text string.
+func (t *Tokenizer) readString(major byte, n uint64, inf bool) ([]byte, error) {
+	t.str = t.str[:0]
+	if !inf {
+		return t.str, t.readN(n)
+	}
+	for {
+		b, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0xFF {
+			return t.str, nil
+		}
+		n, inf, err := t.arg(b & 0x1F)
+		if err != nil {
+			return nil, err
+		}
+		if b>>5 != major || inf {
+			return nil, &jsonlex.SyntaxError{Msg: "invalid chunk of indefinite length string", Pos: t.off - 1}
+		}
+		if err = t.readN(n); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readN appends n bytes to t.str and counts them in t.off.
+func (t *Tokenizer) readN(n uint64) (err error) {
+	var m int
+	t.str, m, err = codec.ReadN(t.r, t.str, n)
+	t.off += uint(m)
+	return err
+}
+
+func (t *Tokenizer) readByte() (byte, error) {
+	if t.hold {
+		t.hold = false
+	} else if _, err := io.ReadFull(t.r, t.one[:]); err != nil {
+		return 0, err
+	}
+	t.off++
+	return t.one[0], nil
+}
+
+func (t *Tokenizer) unread() {
+	t.hold = true
+	t.off--
+}
+
+func (t *Tokenizer) syntax(pos uint, format string, args ...interface{}) (jsonlex.TokenKind, []byte, uint) {
+	return t.fail(&jsonlex.SyntaxError{Msg: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+func (t *Tokenizer) fail(err error) (jsonlex.TokenKind, []byte, uint) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: t.off}
+	}
+	t.err = err
+	return jsonlex.TokenERR, []byte(err.Error()), t.off
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp, frac := uint32(h>>10&0x1F), uint32(h&0x3FF)
+
+	switch {
+	case exp == 0x1F:
+		return math.Float32frombits(sign | 0xFF<<23 | frac<<13)
+	case exp > 0:
+		return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+	}
+	f := float32(frac) / (1 << 24) // subnormal
+	if sign != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,131 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func tokenize(t *testing.T, h string) (string, error) {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	var toks []string
+	tok := NewTokenizer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		if kind != jsonlex.TokenEOF && kind != jsonlex.TokenERR {
+			toks = append(toks, string(load))
+		}
+		return true
+	})
+	tok.Scan(bytes.NewReader(b))
+	return strings.Join(toks, " "), tok.Err()
+}
+
+// examples of RFC 8949, appendix A
+func TestTokenizer_Scan_1(t *testing.T) {
+	for h, e := range map[string]string{
+		"00": "0", "17": "23", "1818": "24", "1903e8": "1000", "1bffffffffffffffff": "18446744073709551615",
+		"c249010000000000000000": "18446744073709551616", "3bffffffffffffffff": "-18446744073709551616",
+		"c349010000000000000000": "-18446744073709551617", "20": "-1", "3863": "-100",
+		"f90000": "0", "f98000": "-0", "f93c00": "1", "fb3ff199999999999a": "1.1", "f93e00": "1.5",
+		"f97bff": "65504", "fa47c35000": "100000", "fa7f7fffff": "3.4028234663852886e+38", "fb7e37e43c8800759c": "1e+300",
+		"f90001": "5.960464477539063e-8", "f90400": "0.00006103515625", "f9c400": "-4", "fbc010666666666666": "-4.1",
+		"f4": "false", "f5": "true", "f6": "null", "f7": "null", "c074323031332d30332d32315432303a30343a30305a": "2013-03-21T20:04:00Z",
+		"4401020304": "AQIDBA==", "60": "", "6161": "a", "62225c": `\"\\`, "62c3bc": "ü", "6161626364": "a cd",
+		"80": "[ ]", "83010203": "[ 1 , 2 , 3 ]", "8301820203820405": "[ 1 , [ 2 , 3 ] , [ 4 , 5 ] ]",
+		"a0": "{ }", "a201020304": "", "a26161016162820203": "{ a : 1 , b : [ 2 , 3 ] }",
+		"826161a161626163": "[ a , { b : c } ]", "5f42010243030405ff": "AQIDBAU=", "7f657374726561646d696e67ff": "streaming",
+		"9fff": "[ ]", "9f018202039f0405ffff": "[ 1 , [ 2 , 3 ] , [ 4 , 5 ] ]", "83018202039f0405ff": "[ 1 , [ 2 , 3 ] , [ 4 , 5 ] ]",
+		"bf61610161629f0203ffff": "{ a : 1 , b : [ 2 , 3 ] }", "bf6346756ef563416d7421ff": "{ Fun : true , Amt : -2 }",
+		"0182f5f6": "1 [ true , null ]",
+	} {
+		r, err := tokenize(t, h)
+		if h == "a201020304" {
+			if err == nil {
+				t.Errorf("unexpected nil error %s", h)
+			}
+			continue
+		}
+		if err != nil || r != e {
+			t.Errorf("unexpected %q %v for %s", r, err, h)
+		}
+	}
+}
+
+func TestTokenizer_Scan_2(t *testing.T) {
+	for _, h := range []string{
+		"18", "1f", "1c", "9f01", "a16161", "bf6161ff", "f97c00", "f97e00", "f8ff", "ff",
+		"62c328", "5f6161ff", "c0", "3f",
+	} {
+		if _, err := tokenize(t, h); err == nil {
+			t.Errorf("unexpected nil error %s", h)
+		}
+	}
+	_, err := tokenize(t, "8201ff")
+	if e, ok := err.(*jsonlex.SyntaxError); !ok || e.Pos != 2 {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestTokenizer_Scan_3(t *testing.T) {
+	for _, s := range []string{
+		`{"a": [1, -2.5, 1e+21, "x\"y\u0001", true, null], "b": {}, "c": []}`,
+		`[18446744073709551616, -18446744073709551617, 0.1, {"": [[]]}] "ü" 42`,
+	} {
+		buf := bytes.Buffer{}
+		if err := NewEncoder(&buf).Encode(strings.NewReader(s)); err != nil {
+			t.Fatalf("unexpected %s", err)
+		}
+		var a, b []string
+		jsonlex.NewLexer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+			a = append(a, string(load))
+			return true
+		}).Scan(strings.NewReader(s))
+
+		c := NewCursor(&buf, nil)
+		for c.Scan() {
+			b = append(b, c.Curr().String())
+		}
+		if c.Err() != nil || strings.Join(a[:len(a)-1], " ") != strings.Join(b, " ") {
+			t.Errorf("unexpected %v %v", b, c.Err())
+		}
+	}
+}
+
+func TestTokenizer_Scan_4(t *testing.T) {
+	var toks []string
+	tok := NewTokenizer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, string(load))
+		return false
+	})
+	r := bytes.NewReader([]byte{0x82, 0x01, 0x02})
+	for i := 0; i < 6; i++ {
+		tok.Scan(r)
+	}
+	if strings.Join(toks, " ") != "[ 1 , 2 ] " || tok.Offset() != 3 || tok.Err() != nil {
+		t.Errorf("unexpected %q", toks)
+	}
+}
+
+// decoded floats parse to the same float64
+func TestTokenizer_Scan_5(t *testing.T) {
+	for h, e := range map[string]float64{
+		"fa5f800000": 1.8446744073709552e19, "f90001": 5.960464477539063e-8, "f90400": 0.00006103515625,
+		"fa7f7fffff": 3.4028234663852886e+38, "fa3dcccccd": 0.10000000149011612, "f9c400": -4,
+	} {
+		r, err := tokenize(t, h)
+		if err != nil {
+			t.Fatalf("unexpected %s", err)
+		}
+		if f, err := strconv.ParseFloat(r, 64); err != nil || f != e {
+			t.Errorf("unexpected %s for %s", r, h)
+		}
+	}
+}
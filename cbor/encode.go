@@ -0,0 +1,212 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package cbor converts between the token stream of the jsonlex.Lexer
+// and CBOR (RFC 8949).
+package cbor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/number"
+)
+
+// Encoder writes the tokens of a JSON stream as CBOR data items,
+// incrementally: objects and arrays are encoded with indefinite length.
+// The structure of the token stream is validated. Integer lexemes are
+// encoded as the smallest integer, or as bignum if they exceed 64 bits.
+// Lexemes with fraction or exponent, which denote an integer in the
+// range of int64 or uint64, like 1e2 or 100.0, are encoded as integer
+// too. Other number lexemes, and -0.0, are rounded to the nearest
+// float64, which is encoded as the smallest float (half, single or
+// double precision) holding it exactly. Decimal digits beyond float64
+// precision are lost.
+type Encoder struct {
+	w   *bufio.Writer
+	g   jsonlex.Grammar
+	err error
+	buf []byte
+	str []byte
+}
+
+// NewEncoder creates an Encoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode reads the JSON stream from r and writes its values. A stream
+// of concatenated values results in a sequence of data items.
+func (e *Encoder) Encode(r io.Reader) error {
+	l := jsonlex.NewLexer(e.Yield)
+	l.Scan(r)
+
+	if err := l.Err(); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// Yield consumes a token, it is a jsonlex.Yield function.
+// Buffered data is flushed on jsonlex.TokenEOF.
+func (e *Encoder) Yield(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+	if e.err != nil {
+		return false
+	}
+
+	switch kind {
+	case jsonlex.TokenEOF:
+		if !e.g.Done() {
+			e.err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: pos}
+		}
+		_ = e.Flush()
+		return false
+	case jsonlex.TokenERR:
+		e.err = errors.New(string(load))
+		return false
+	}
+	if _, ok := e.g.Push(kind); !ok {
+		e.err = &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+		return false
+	}
+	b := e.buf[:0]
+
+	switch kind {
+	case jsonlex.TokenCOL, jsonlex.TokenCOM:
+		return true
+	case jsonlex.TokenLCB:
+		b = append(b, 0xBF)
+	case jsonlex.TokenLSB:
+		b = append(b, 0x9F)
+	case jsonlex.TokenRCB, jsonlex.TokenRSB:
+		b = append(b, 0xFF)
+	case jsonlex.TokenSTR, jsonlex.TokenKEY:
+		s, err := jsonlex.Unescape(e.str[:0], load)
+		if err != nil {
+			e.err = &jsonlex.SyntaxError{Msg: err.Error(), Pos: pos}
+			return false
+		}
+		e.str = s
+		b = appendHead(b, 3, uint64(len(s)))
+		b = append(b, s...)
+	case jsonlex.TokenLIT:
+		switch load[0] {
+		case 'f':
+			b = append(b, 0xF4)
+		case 't':
+			b = append(b, 0xF5)
+		default:
+			b = append(b, 0xF6)
+		}
+	default: // numbers
+		var err error
+		if b, err = appendNumber(b, load); err != nil {
+			e.err = &jsonlex.SyntaxError{Msg: err.Error(), Pos: pos}
+			return false
+		}
+	}
+
+	e.buf = b
+	_, e.err = e.w.Write(b)
+	return e.err == nil
+}
+
+// Flush writes buffered data to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.w.Flush()
+	return e.err
+}
+
+// appendHead appends the initial byte and argument of a data item.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, m|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		return append(b, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(b, m|27, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32),
+		byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendNumber(b []byte, load []byte) ([]byte, error) {
+	if s, ok := number.Integral(load); ok {
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return appendHead(b, 0, u), nil
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if i == 0 {
+				return appendHead(b, 0, 0), nil // -0
+			}
+			return appendHead(b, 1, uint64(-1-i)), nil
+		}
+		// bignum (tag 2 or 3)
+		n, _ := new(big.Int).SetString(s, 10)
+		tag := byte(0xC2)
+		if n.Sign() < 0 {
+			if tag, n = 0xC3, n.Not(n); n.IsUint64() { // -1-n
+				return appendHead(b, 1, n.Uint64()), nil
+			}
+		}
+		mag := n.Bytes()
+		b = append(b, tag)
+		b = appendHead(b, 2, uint64(len(mag)))
+		return append(b, mag...), nil
+	}
+
+	f, err := strconv.ParseFloat(string(load), 64)
+	if err != nil {
+		return nil, fmt.Errorf("number %s out of range", load)
+	}
+	if h, ok := half(f); ok {
+		return append(b, 0xF9, byte(h>>8), byte(h)), nil
+	}
+	if f32 := float32(f); float64(f32) == f {
+		u := math.Float32bits(f32)
+		return append(b, 0xFA, byte(u>>24), byte(u>>16), byte(u>>8), byte(u)), nil
+	}
+	u := math.Float64bits(f)
+	return append(b, 0xFB, byte(u>>56), byte(u>>48), byte(u>>40), byte(u>>32),
+		byte(u>>24), byte(u>>16), byte(u>>8), byte(u)), nil
+}
+
+// half returns the half precision representation
+// of f and whether it represents f exactly.
+func half(f float64) (uint16, bool) {
+	sign := uint16(0)
+	if math.Signbit(f) {
+		sign, f = 0x8000, -f
+	}
+	if f == 0 {
+		return sign, true
+	}
+	frac, exp := math.Frexp(f) // f = frac * 2^exp, 0.5 <= frac < 1
+	if e := exp - 1; e >= -14 {
+		if e > 15 {
+			return 0, false
+		}
+		m := (2*frac - 1) * 1024
+		if m != math.Trunc(m) {
+			return 0, false
+		}
+		return sign | uint16(e+15)<<10 | uint16(m), true
+	}
+	m := f * (1 << 24) // subnormal, f = m * 2^-24
+	if m != math.Trunc(m) || m >= 1024 {
+		return 0, false
+	}
+	return sign | uint16(m), true
+}
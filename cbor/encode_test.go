@@ -0,0 +1,78 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func encode(t *testing.T, s string) string {
+	buf := bytes.Buffer{}
+	if err := NewEncoder(&buf).Encode(strings.NewReader(s)); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// examples of RFC 8949, appendix A, integral floats are encoded as integers
+func TestEncoder_Encode_1(t *testing.T) {
+	for s, e := range map[string]string{
+		`0`: "00", `23`: "17", `24`: "1818", `100`: "1864", `1000`: "1903e8", `1000000`: "1a000f4240",
+		`1000000000000`: "1b000000e8d4a51000", `18446744073709551615`: "1bffffffffffffffff",
+		`18446744073709551616`: "c249010000000000000000", `-18446744073709551616`: "3bffffffffffffffff",
+		`-18446744073709551617`: "c349010000000000000000", `-1`: "20", `-10`: "29", `-100`: "3863",
+		`-0`: "00", `0.0`: "00", `-0.0`: "f98000", `1.0`: "01", `1.1`: "fb3ff199999999999a", `0.5`: "f93800",
+		`1.5`: "f93e00", `65504.0`: "19ffe0", `100000.0`: "1a000186a0", `3.4028234663852886e+38`: "fa7f7fffff",
+		`1.0e+300`: "fb7e37e43c8800759c", `5.960464477539063e-8`: "f90001", `0.00006103515625`: "f90400",
+		`-4.0`: "23", `-4.1`: "fbc010666666666666", `false`: "f4", `true`: "f5", `null`: "f6",
+		`""`: "60", `"a"`: "6161", `"IETF"`: "6449455446", `"\"\\"`: "62225c", `"ü"`: "62c3bc",
+		`"水"`: "63e6b0b4", `"𐅑"`: "64f0908591",
+		`[]`: "9fff", `[1, [2, 3], [4, 5]]`: "9f019f0203ff9f0405ffff", `{}`: "bfff",
+		`{"a": 1, "b": [2, 3]}`: "bf61610161629f0203ffff", `1 "a"`: "016161",
+		// integral lexemes with fraction or exponent
+		`1e2`: "1864", `1.8446744073709551616e19`: "fa5f800000",
+	} {
+		if r := encode(t, s); r != e {
+			t.Errorf("unexpected %s for %s", r, s)
+		}
+	}
+}
+
+func TestEncoder_Encode_2(t *testing.T) {
+	for _, s := range []string{`[1`, `]`, `[}`, `1e400`, `"\x"`, `@`, `{"a"}`, `{1:2}`, `[1 2]`, `[1,]`, `{"a":1,}`, `1 ,`} {
+		if err := NewEncoder(&bytes.Buffer{}).Encode(strings.NewReader(s)); err == nil {
+			t.Errorf("unexpected nil error %s", s)
+		}
+	}
+}
+
+func TestEncoder_Yield_1(t *testing.T) {
+	buf := bytes.Buffer{}
+	e := NewEncoder(&buf)
+
+	l := jsonlex.NewLexer(e.Yield, jsonlex.LexerOptEmitKeys, jsonlex.LexerOptEmitNumberKinds)
+	l.Scan(strings.NewReader(`{"a": [1.5, 2]}`))
+
+	if e.Flush() != nil || hex.EncodeToString(buf.Bytes()) != "bf61619ff93e0002ffff" {
+		t.Errorf("unexpected %x", buf.Bytes())
+	}
+}
+
+func TestEncoder_Yield_2(t *testing.T) {
+	for _, s := range []string{`{"a"}`, `{1:2}`, `[1 2]`, `{"a":1 "b":2}`, `[1:2]`} {
+		buf := bytes.Buffer{}
+		e := NewEncoder(&buf)
+		jsonlex.NewLexer(e.Yield, jsonlex.LexerOptEmitKeys).Scan(strings.NewReader(s))
+
+		var err *jsonlex.SyntaxError
+		if !errors.As(e.Flush(), &err) {
+			t.Errorf("unexpected %v for %s", e.Flush(), s)
+		}
+	}
+}
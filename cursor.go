@@ -3,6 +3,9 @@
 package jsonlex
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -10,11 +13,20 @@ type (
 	// Cursor allows traversing the token stream.
 	Cursor struct {
 		reader  io.Reader
+		context context.Context
 		filter  Filter
 		lexer   *Lexer
-		lastTok Token
-		currTok Token
-		nextTok Token
+		tok     Tokenizer
+		newTok  func(Yield) Tokenizer
+		ring    []Token // history, current and lookahead tokens
+		mask    int     // len(ring)-1, len(ring) is a power of two
+		hist    int     // size of history
+		ahead   int     // size of lookahead
+		head    int     // absolute index of the current token
+		tail    int     // absolute index of the next token to be scanned
+		err     error   // cause of the jsonlex.TokenERR
+		scan    bool    // whether Scan() has been invoked
+		lopts   []lexerOpt
 	}
 
 	// Token is a container for token information.
@@ -27,6 +39,17 @@ type (
 	// TokenKind denotes the type of token.
 	TokenKind uint8
 
+	// Tokenizer is a source of tokens other than the Lexer, e.g. the
+	// binary tokenizers of the cbor and msgpack subpackages. Like the
+	// Lexer, it invokes its Yield function for each token consumed
+	// from the reader until the Yield function returns false or a
+	// jsonlex.TokenEOF or jsonlex.TokenERR has been yielded.
+	Tokenizer interface {
+		Scan(r io.Reader)
+		Err() error
+		Offset() uint
+	}
+
 	// Filter is a callback function. It will be invoked when
 	// the cursor is advanced. The callback must return whether
 	// the token is accepted (true) or should dropped (false).
@@ -35,33 +58,103 @@ type (
 )
 
 // NewCursor creates and prepares a Cursor.
-func NewCursor(r io.Reader, f Filter) *Cursor {
+func NewCursor(r io.Reader, f Filter, opts ...cursorOpt) *Cursor {
+	return newCursor(nil, r, f, opts...)
+}
+
+// NewCursorContext creates and prepares a Cursor which checks the
+// given context while advancing. When the context is done, the
+// Cursor will provide a jsonlex.TokenERR carrying ctx.Err().
+func NewCursorContext(ctx context.Context, r io.Reader, f Filter, opts ...cursorOpt) *Cursor {
+	return newCursor(ctx, r, f, opts...)
+}
+
+type cursorOpt func(*Cursor)
+
+// CursorOptHistory sets the number of previous tokens
+// available by LastN(). The default is 1.
+func CursorOptHistory(n int) cursorOpt {
+	return func(c *Cursor) {
+		if n > 1 {
+			c.hist = n
+		}
+	}
+}
+
+// CursorOptLookahead sets the number of next tokens
+// available by PeekN(). The default is 1.
+func CursorOptLookahead(n int) cursorOpt {
+	return func(c *Cursor) {
+		if n > 1 {
+			c.ahead = n
+		}
+	}
+}
+
+// CursorOptLexer passes options to the Lexer of the Cursor. Together
+// with LexerOptEnableUnreadBuffer and a reader implementing the
+// UnreadableReader, the Cursor does not read past the current Token
+// unless Peek() or PeekN() ask for more. This allows to share the
+// reader with other consumers.
+func CursorOptLexer(opts ...lexerOpt) cursorOpt {
+	return func(c *Cursor) {
+		c.lopts = append(c.lopts, opts...)
+	}
+}
+
+// CursorOptTokenizer replaces the Lexer of the Cursor with the
+// Tokenizer returned by fn, which is invoked with the Yield function
+// of the Cursor. Lexer options are ignored then. The context of a
+// Cursor created by NewCursorContext() is checked before each token.
+// The SeekableCursor ignores this option.
+func CursorOptTokenizer(fn func(Yield) Tokenizer) cursorOpt {
+	return func(c *Cursor) {
+		c.newTok = fn
+	}
+}
+
+func newCursor(ctx context.Context, r io.Reader, f Filter, opts ...cursorOpt) *Cursor {
 	c := &Cursor{
-		reader: r,
-		filter: f,
+		reader:  r,
+		context: ctx,
+		filter:  f,
+		hist:    1,
+		ahead:   1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	n := 1
+	for n < c.hist+1+c.ahead {
+		n <<= 1
 	}
+	c.ring, c.mask = make([]Token, n), n-1
 
 	yield := func(kind TokenKind, load []byte, pos uint) bool {
-		if c.currTok.Is(TokenERR) {
-			return false
-		}
 		if c.filter != nil && !c.filter(kind, load) {
 			return true
 		}
 
+		if kind.Is(TokenERR) {
+			if c.err = c.tok.Err(); c.err == nil {
+				c.err = errors.New(string(load))
+			}
+		}
+
 		val := make([]byte, len(load))
 		copy(val, load)
-
-		c.lastTok = c.currTok
-		c.currTok = c.nextTok
-		c.nextTok = Token{kind, val, pos}
+		c.push(Token{kind, val, pos})
 
 		return false
 	}
 
-	c.lexer = NewLexer(yield)
-	c.Next()
-	c.Next()
+	if c.newTok != nil {
+		c.tok = c.newTok(yield)
+	} else {
+		c.lexer = NewLexer(yield, c.lopts...)
+		c.tok = c.lexer
+	}
+	c.fill(0)
 
 	return c
 }
@@ -69,27 +162,139 @@ func NewCursor(r io.Reader, f Filter) *Cursor {
 // Last returns the previous Token in stream.
 // The underlying scanner position is not modified.
 func (c *Cursor) Last() Token {
-	return c.lastTok
+	return c.LastN(1)
+}
+
+// LastN returns the k-th previous Token in stream, LastN(1)
+// equals Last(). The underlying scanner position is not modified.
+// LastN panics if k exceeds the history size of the Cursor.
+func (c *Cursor) LastN(k int) Token {
+	if k < 0 || k > c.hist {
+		panic(fmt.Sprintf("jsonlex: LastN(%d) exceeds history of %d", k, c.hist))
+	}
+	return c.at(c.head - k)
 }
 
 // Curr function returns the current Token in stream.
 // The underlying scanner position is not modified.
 func (c *Cursor) Curr() Token {
-	return c.currTok
+	return c.ring[c.head&c.mask]
 }
 
-// Peek returns the next Token in stream.
-// The underlying scanner position is not modified.
+// Peek returns the next Token in stream. The Token is scanned
+// lazily, but the position of the Cursor is not modified.
 func (c *Cursor) Peek() Token {
-	return c.nextTok
+	if c.head+1 >= c.tail {
+		c.fill(1)
+	}
+	return c.ring[(c.head+1)&c.mask]
+}
+
+// PeekN returns the k-th next Token in stream, PeekN(1) equals
+// Peek(). The tokens are scanned lazily, but the position of
+// the Cursor is not modified. PeekN panics if k exceeds the
+// lookahead size of the Cursor.
+func (c *Cursor) PeekN(k int) Token {
+	if k < 0 || k > c.ahead {
+		panic(fmt.Sprintf("jsonlex: PeekN(%d) exceeds lookahead of %d", k, c.ahead))
+	}
+	c.fill(k)
+	return c.at(c.head + k)
 }
 
 // Next returns the next Token in stream. In contrast to
 // the other methods, the underlying scanner position is
 // modified.
+//
+// Errors are sticky: once the Cursor reached a jsonlex.TokenERR,
+// Next() keeps returning it and Err() reports its cause. After a
+// jsonlex.TokenEOF, Next() keeps returning jsonlex.TokenEOF.
 func (c *Cursor) Next() Token {
-	c.lexer.Scan(c.reader)
-	return c.currTok
+	if c.head++; c.head >= c.tail {
+		c.fill(0)
+	}
+	return c.ring[c.head&c.mask]
+}
+
+// Scan advances the Cursor like Next(), except for the first
+// invocation which leaves the Cursor on the first Token. It
+// returns false when the Cursor is Done(). This allows loops
+// in the style of bufio.Scanner:
+//
+//	for c.Scan() {
+//	    tok := c.Curr()
+//	}
+//	if err := c.Err(); err != nil {
+//	    ...
+//	}
+func (c *Cursor) Scan() bool {
+	if c.scan {
+		c.Next()
+	}
+	c.scan = true
+	return !c.Done()
+}
+
+// Done returns whether the current Token is a
+// jsonlex.TokenEOF or jsonlex.TokenERR.
+func (c *Cursor) Done() bool {
+	t := c.Curr()
+	return t.Is(TokenEOF) || t.Is(TokenERR)
+}
+
+// Err returns the cause of the jsonlex.TokenERR when the Cursor
+// reached it, nil otherwise. Reaching jsonlex.TokenEOF is not
+// considered an error.
+func (c *Cursor) Err() error {
+	if c.Curr().Is(TokenERR) {
+		return c.err
+	}
+	return nil
+}
+
+// fill scans until the current Token and k tokens
+// of lookahead are available. Once
+// a jsonlex.TokenEOF or jsonlex.TokenERR has been scanned,
+// it is repeated instead.
+func (c *Cursor) fill(k int) {
+	for c.tail <= c.head+k {
+		if c.tail > 0 {
+			if t := &c.ring[(c.tail-1)&c.mask]; t.Kind == TokenEOF || t.Kind == TokenERR {
+				c.push(*t)
+				continue
+			}
+		}
+		n := c.tail
+		switch {
+		case c.context == nil && c.lexer != nil:
+			c.lexer.Scan(c.reader)
+		case c.context == nil:
+			c.tok.Scan(c.reader)
+		case c.lexer != nil:
+			c.lexer.ScanContext(c.context, c.reader)
+		case c.context.Err() != nil:
+			c.err = c.context.Err()
+			c.push(Token{Kind: TokenERR, Load: []byte(c.err.Error()), Pos: c.tok.Offset()})
+		default:
+			c.tok.Scan(c.reader)
+		}
+		if c.tail == n {
+			// the filter dropped the final token
+			c.push(Token{Kind: TokenEOF, Pos: c.tok.Offset()})
+		}
+	}
+}
+
+func (c *Cursor) push(t Token) {
+	c.ring[c.tail&c.mask] = t
+	c.tail++
+}
+
+func (c *Cursor) at(i int) Token {
+	if i < 0 {
+		return Token{}
+	}
+	return c.ring[i&c.mask]
 }
 
 // Is is a convenience function.
@@ -114,6 +319,9 @@ const (
 	TokenRSB                  // ] right square bracket
 	TokenLCB                  // { left curly brace
 	TokenRCB                  // } right curly brace
+	TokenKEY                  // "..." object member name, see LexerOptEmitKeys
+	TokenINT                  // integer number, see LexerOptEmitNumberKinds
+	TokenFLT                  // float number, see LexerOptEmitNumberKinds
 
 	scanning
 )
@@ -122,3 +330,15 @@ const (
 func (k TokenKind) Is(kind TokenKind) bool {
 	return k == kind
 }
+
+// plain maps the kinds refined by LexerOptEmitKeys and
+// LexerOptEmitNumberKinds to jsonlex.TokenSTR and jsonlex.TokenNUM.
+func (k TokenKind) plain() TokenKind {
+	switch k {
+	case TokenKEY:
+		return TokenSTR
+	case TokenINT, TokenFLT:
+		return TokenNUM
+	}
+	return k
+}
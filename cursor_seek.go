@@ -0,0 +1,99 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"io"
+)
+
+type (
+	// SeekableCursor is a Cursor over an io.ReaderAt, e.g. an os.File or
+	// a memory mapped file. It can backtrack to a Mark() and jump to the
+	// Pos of any Token seen before, without reopening the stream.
+	SeekableCursor struct {
+		*Cursor
+		reader *offsetReader
+	}
+
+	// Mark is a position of a SeekableCursor, see Mark() and Reset().
+	Mark struct {
+		snap []byte
+		ring []Token
+		head int
+		tail int
+		err  error
+		scan bool
+	}
+
+	offsetReader struct {
+		r   io.ReaderAt
+		off int64
+	}
+)
+
+// NewSeekableCursor creates and prepares a SeekableCursor.
+func NewSeekableCursor(r io.ReaderAt, f Filter, opts ...cursorOpt) *SeekableCursor {
+	or := &offsetReader{r: r}
+	opts = append(opts[:len(opts):len(opts)], func(c *Cursor) { c.newTok = nil })
+	return &SeekableCursor{
+		Cursor: newCursor(nil, or, f, opts...),
+		reader: or,
+	}
+}
+
+// Mark returns the current position of the cursor,
+// which can be returned to with Reset().
+func (c *SeekableCursor) Mark() Mark {
+	return Mark{
+		snap: c.lexer.Snapshot(),
+		ring: append([]Token(nil), c.ring...),
+		head: c.head,
+		tail: c.tail,
+		err:  c.err,
+		scan: c.scan,
+	}
+}
+
+// Reset returns the cursor to the given Mark.
+func (c *SeekableCursor) Reset(m Mark) error {
+	if err := c.lexer.Restore(m.snap); err != nil {
+		return err
+	}
+	c.reader.off = int64(c.lexer.Offset())
+	copy(c.ring, m.ring)
+	c.head, c.tail, c.err, c.scan = m.head, m.tail, m.err, m.scan
+
+	return nil
+}
+
+// SeekTo moves the cursor to the byte position pos, which should be the
+// Pos of a Token. The Token found there becomes the current Token, the
+// history is discarded. Token positions remain absolute. The next Scan()
+// does not advance, so that a Scan() loop continues with this Token.
+//
+// The Lexer starts over at pos without knowledge of the enclosing
+// containers: Depth(), InObject() and InArray() are relative to pos, and
+// member names are not recognized as such (LexerOptEmitKeys, duplicate
+// key checks) until the next object opens. Use Mark() and Reset() to
+// return to a position with the structure intact.
+func (c *SeekableCursor) SeekTo(pos uint) Token {
+	c.lexer.reset()
+	c.lexer.bpos = pos
+	c.reader.off = int64(pos)
+	for i := range c.ring {
+		c.ring[i] = Token{}
+	}
+	c.head, c.tail, c.err, c.scan = 0, 0, nil, false
+	c.fill(0)
+
+	return c.Curr()
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.r.ReadAt(p, r.off)
+	r.off += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,87 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeekableCursor_Mark_1(t *testing.T) {
+	c := NewSeekableCursor(strings.NewReader(`{"a": [1, 2], "b": true}`), nil)
+
+	c.Next()
+	c.Next()
+	m := c.Mark() // on :
+
+	var e []string
+	for ; !c.Curr().Is(TokenEOF); c.Next() {
+		e = append(e, c.Curr().String())
+	}
+
+	if err := c.Reset(m); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	if c.Last().String() != "a" || c.Curr().String() != ":" || c.Peek().String() != "[" {
+		t.Errorf("unexpected %s %s %s", c.Last(), c.Curr(), c.Peek())
+	}
+
+	var a []string
+	for ; !c.Curr().Is(TokenEOF); c.Next() {
+		a = append(a, c.Curr().String())
+	}
+	if strings.Join(a, " ") != strings.Join(e, " ") {
+		t.Errorf("unexpected %v", a)
+	}
+}
+
+func TestSeekableCursor_SeekTo_1(t *testing.T) {
+	c := NewSeekableCursor(strings.NewReader(`{"a": [10, 2], "b": true}`), nil)
+
+	var num Token
+	for ; !c.Curr().Is(TokenEOF); c.Next() {
+		if c.Curr().Is(TokenNUM) && num.Load == nil {
+			num = c.Curr()
+		}
+	}
+
+	tok := c.SeekTo(num.Pos)
+	if !tok.Is(TokenNUM) || tok.String() != "10" || tok.Pos != 7 {
+		t.Errorf("unexpected %v", tok)
+	}
+	if c.Peek().String() != "," || c.Peek().Pos != 9 {
+		t.Errorf("unexpected %v", c.Peek())
+	}
+	if tok = c.Next(); tok.String() != "," || c.Last().String() != "10" {
+		t.Errorf("unexpected %v", tok)
+	}
+
+	if tok = c.SeekTo(0); !tok.Is(TokenLCB) || c.Peek().String() != "a" {
+		t.Errorf("unexpected %v", tok)
+	}
+}
+
+func TestSeekableCursor_SeekTo_2(t *testing.T) {
+	c := NewSeekableCursor(strings.NewReader(`[1]`), nil)
+
+	if tok := c.SeekTo(3); !tok.Is(TokenEOF) || tok.Pos != 3 {
+		t.Errorf("unexpected %v", tok)
+	}
+	if tok := c.SeekTo(1); tok.String() != "1" || c.Peek().String() != "]" {
+		t.Errorf("unexpected %v", tok)
+	}
+}
+
+func TestSeekableCursor_SeekTo_3(t *testing.T) {
+	c := NewSeekableCursor(strings.NewReader(`[1, 2, 3]`), nil)
+
+	var toks []string
+	for c.Scan() {
+		if toks = append(toks, c.Curr().String()); c.Curr().String() == "3" && len(toks) < 8 {
+			c.SeekTo(4)
+		}
+	}
+	if strings.Join(toks, " ") != "[ 1 , 2 , 3 2 , 3 ]" {
+		t.Errorf("unexpected %v", toks)
+	}
+}
@@ -4,7 +4,9 @@ package jsonlex
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"strings"
 	"testing"
 )
 
@@ -72,3 +74,207 @@ func TestCursor_3(t *testing.T) {
 		t.Errorf("unexpected")
 	}
 }
+
+func TestCursorContext_1(t *testing.T) {
+	s := `{ "foo": -1 }`
+	r := bytes.NewReader([]byte(s))
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewCursorContext(ctx, r, nil)
+
+	if n := c.Curr(); !n.Is(TokenLCB) {
+		t.Errorf("unexpected")
+	}
+	if n := c.Peek(); !n.Is(TokenSTR) {
+		t.Errorf("unexpected")
+	}
+	cancel()
+
+	if n := c.Next(); !n.Is(TokenSTR) {
+		t.Errorf("unexpected")
+	}
+	if n := c.Next(); !n.Is(TokenERR) {
+		t.Errorf("unexpected")
+	}
+	if c.Curr().String() != context.Canceled.Error() {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestCursor_PeekN_1(t *testing.T) {
+	s := `{ "type": "circle", "r": 2 }`
+	c := NewCursor(bytes.NewReader([]byte(s)), nil, CursorOptLookahead(4))
+
+	if n := c.PeekN(3); n.String() != "circle" {
+		t.Errorf("unexpected %s", n)
+	}
+	if n := c.PeekN(0); !n.Is(TokenLCB) {
+		t.Errorf("unexpected %s", n)
+	}
+	if n := c.Next(); !n.Is(TokenSTR) || c.PeekN(4).String() != "r" {
+		t.Errorf("unexpected %s", n)
+	}
+	for i := 0; i < 8; i++ {
+		c.Next()
+	}
+	if n := c.PeekN(4); !n.Is(TokenEOF) || !c.Curr().Is(TokenEOF) {
+		t.Errorf("unexpected %s", n)
+	}
+}
+
+func TestCursor_PeekN_2(t *testing.T) {
+	r := bytes.NewReader([]byte(`[1, 2, 3]`))
+	c := NewCursor(r, nil, CursorOptLookahead(3))
+
+	if r.Len() != 8 {
+		t.Errorf("unexpected %d", r.Len())
+	}
+	if c.PeekN(3); r.Len() != 3 {
+		t.Errorf("unexpected %d", r.Len())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unexpected")
+		}
+	}()
+	c.PeekN(4)
+}
+
+func TestCursor_LastN_1(t *testing.T) {
+	s := `[1, 2, 3]`
+	c := NewCursor(bytes.NewReader([]byte(s)), nil, CursorOptHistory(3))
+
+	if n := c.LastN(3); !n.Is(TokenEOF) || n.Load != nil {
+		t.Errorf("unexpected %s", n)
+	}
+	c.Next()
+	c.Next()
+	c.Next()
+	if c.LastN(1).String() != "," || c.LastN(2).String() != "1" || c.LastN(3).String() != "[" {
+		t.Errorf("unexpected %s %s %s", c.LastN(1), c.LastN(2), c.LastN(3))
+	}
+	if c.Last().String() != "," || c.LastN(0).String() != "2" {
+		t.Errorf("unexpected %s", c.Last())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unexpected")
+		}
+	}()
+	c.LastN(4)
+}
+
+func TestCursor_Scan_1(t *testing.T) {
+	c := NewCursor(bytes.NewReader([]byte(`[1, true]`)), nil)
+
+	var toks []string
+	for c.Scan() {
+		toks = append(toks, c.Curr().String())
+	}
+	if strings.Join(toks, " ") != "[ 1 , true ]" {
+		t.Errorf("unexpected %v", toks)
+	}
+	if !c.Done() || c.Err() != nil || c.Scan() {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestCursor_Err_1(t *testing.T) {
+	c := NewCursor(bytes.NewReader([]byte(`[1, x]`)), nil)
+
+	i := 0
+	for c.Scan() {
+		if c.Err() != nil {
+			t.Errorf("unexpected %s", c.Err())
+		}
+		i++
+	}
+	if i != 3 || !c.Done() {
+		t.Errorf("unexpected %d", i)
+	}
+	err, ok := c.Err().(*SyntaxError)
+	if !ok || err.Pos != 4 {
+		t.Fatalf("unexpected %v", c.Err())
+	}
+	if c.Next(); !c.Curr().Is(TokenERR) || c.Err() != err {
+		t.Errorf("unexpected %v", c.Err())
+	}
+}
+
+func TestCursor_Err_2(t *testing.T) {
+	c := NewCursor(&FaultyReader{}, nil)
+
+	for c.Scan() {
+	}
+	if c.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected %v", c.Err())
+	}
+}
+
+func TestCursor_Lexer_1(t *testing.T) {
+	r := bytes.NewReader([]byte(`{"a": 1} 42 [true]`))
+
+	c := NewCursor(r, nil, CursorOptLexer(LexerOptEnableUnreadBuffer))
+	for !c.Curr().Is(TokenRCB) {
+		c.Next()
+	}
+	if r.Len() != 10 {
+		t.Errorf("unexpected %d", r.Len())
+	}
+
+	c = NewCursor(r, nil, CursorOptLexer(LexerOptEnableUnreadBuffer))
+	if n := c.Curr(); n.String() != "42" || n.Pos != 1 {
+		t.Errorf("unexpected %v", n)
+	}
+	if r.Len() != 7 {
+		t.Errorf("unexpected %d", r.Len())
+	}
+
+	b := make([]byte, r.Len())
+	_, _ = r.Read(b)
+	if string(b) != " [true]" {
+		t.Errorf("unexpected %s", b)
+	}
+}
+
+type sliceTokenizer struct {
+	yield Yield
+	toks  []Token
+	off   uint
+}
+
+func (t *sliceTokenizer) Scan(io.Reader) {
+	for ; int(t.off) < len(t.toks); t.off++ {
+		if tok := t.toks[t.off]; !t.yield(tok.Kind, tok.Load, tok.Pos) {
+			t.off++
+			return
+		}
+	}
+	t.yield(TokenEOF, nil, t.off)
+}
+
+func (t *sliceTokenizer) Err() error   { return nil }
+func (t *sliceTokenizer) Offset() uint { return t.off }
+
+func TestCursor_Tokenizer_1(t *testing.T) {
+	toks := []Token{{TokenLSB, []byte("["), 0}, {TokenNUM, []byte("1"), 1}, {TokenRSB, []byte("]"), 2}}
+	opt := CursorOptTokenizer(func(y Yield) Tokenizer {
+		return &sliceTokenizer{yield: y, toks: toks}
+	})
+
+	c := NewCursor(nil, nil, opt)
+	var s []string
+	for c.Scan() {
+		s = append(s, c.Curr().String())
+	}
+	if strings.Join(s, " ") != "[ 1 ]" || c.Err() != nil || c.Curr().Pos != 3 {
+		t.Errorf("unexpected %v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c = NewCursorContext(ctx, nil, nil, opt)
+	cancel()
+
+	if n := c.Next(); !n.Is(TokenERR) || c.Err() != context.Canceled || !c.Last().Is(TokenLSB) {
+		t.Errorf("unexpected %v", n)
+	}
+}
@@ -0,0 +1,547 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DecodeTypeError describes a token which can not be
+// stored into a Go value of the given type.
+type DecodeTypeError struct {
+	Load string       // token load
+	Type reflect.Type // type of Go value
+	Pos  uint         // token position in stream
+}
+
+func (e *DecodeTypeError) Error() string {
+	return fmt.Sprintf("cannot decode %q into Go value of type %s", e.Load, e.Type)
+}
+
+// Decode consumes the value starting with the current token and stores
+// it into the value pointed to by v. Afterwards, the Cursor points to the
+// token following the consumed value. The mapping of JSON values to Go
+// values follows encoding/json.Unmarshal: structs (honouring `json` tags),
+// maps, slices, arrays, pointers, interface{} and scalars are supported,
+// as well as encoding.TextUnmarshaler for strings.
+//
+// Decode expects an unfiltered token stream, a Filter dropping colons or
+// commas will make it fail. After a failure, the Cursor position is
+// undefined.
+func (c *Cursor) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errDecodeNonPointer
+	}
+	return c.decode(rv.Elem())
+}
+
+func (c *Cursor) decode(rv reflect.Value) error {
+	tok := c.curr()
+
+	if err := c.check(tok); err != nil {
+		return err
+	}
+	if tok.Is(TokenLIT) && tok.Load[0] == 'n' {
+		switch rv.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			rv.Set(reflect.Zero(rv.Type()))
+		}
+		c.next()
+		return nil
+	}
+
+	rv = indirect(rv)
+
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		val, err := c.decodeAny()
+		if err == nil && val != nil {
+			rv.Set(reflect.ValueOf(val))
+		}
+		return err
+	}
+
+	switch tok.Kind {
+	case TokenLCB:
+		return c.decodeObject(rv)
+	case TokenLSB:
+		return c.decodeArray(rv)
+	case TokenSTR, TokenNUM, TokenLIT:
+		if err := c.decodeScalar(rv, tok); err != nil {
+			return err
+		}
+		c.next()
+		return nil
+	}
+	return c.unexpected(tok)
+}
+
+func (c *Cursor) decodeScalar(rv reflect.Value, tok Token) error {
+	typeErr := &DecodeTypeError{Load: string(tok.Load), Type: rv.Type(), Pos: tok.Pos}
+
+	if tok.Is(TokenSTR) {
+		s, err := Unescape(nil, tok.Load)
+		if err != nil {
+			return &SyntaxError{Msg: err.Error(), Pos: tok.Pos}
+		}
+		if rv.CanAddr() {
+			if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+				return u.UnmarshalText(s)
+			}
+		}
+		switch {
+		case rv.Kind() == reflect.String:
+			rv.SetString(string(s))
+		case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+			b := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+			n, err := base64.StdEncoding.Decode(b, s)
+			if err != nil {
+				return typeErr
+			}
+			rv.SetBytes(b[:n])
+		default:
+			return typeErr
+		}
+		return nil
+	}
+
+	if tok.Is(TokenLIT) {
+		if rv.Kind() != reflect.Bool {
+			return typeErr
+		}
+		rv.SetBool(tok.Load[0] == 't')
+		return nil
+	}
+
+	s := string(tok.Load)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || rv.OverflowInt(n) {
+			return typeErr
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || rv.OverflowUint(n) {
+			return typeErr
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return typeErr
+		}
+		rv.SetFloat(n)
+	default:
+		return typeErr
+	}
+	return nil
+}
+
+func (c *Cursor) decodeObject(rv reflect.Value) error {
+	var (
+		fields []decodeField
+		mkey   reflect.Value
+		mval   reflect.Value
+	)
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		fields = decodeFields(rv.Type())
+	case reflect.Map:
+		switch rv.Type().Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return c.typeError(rv)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		mkey = reflect.New(rv.Type().Key()).Elem()
+		mval = reflect.New(rv.Type().Elem()).Elem()
+	default:
+		return c.typeError(rv)
+	}
+
+	return c.members(func(key []byte, pos uint) error {
+		if rv.Kind() == reflect.Struct {
+			f, ok := findField(fields, key)
+			if !ok {
+				return c.skip()
+			}
+			fv, err := fieldByIndex(rv, f.index)
+			if err != nil {
+				return err
+			}
+			return c.decode(fv)
+		}
+
+		if mkey.Kind() == reflect.String {
+			mkey.SetString(string(key))
+		} else if err := c.decodeScalar(mkey, Token{TokenNUM, key, pos}); err != nil {
+			return err
+		}
+		mval.Set(reflect.Zero(mval.Type()))
+		if err := c.decode(mval); err != nil {
+			return err
+		}
+		rv.SetMapIndex(mkey, mval)
+		return nil
+	})
+}
+
+func (c *Cursor) decodeArray(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+		}
+		rv.SetLen(0)
+	case reflect.Array:
+	default:
+		return c.typeError(rv)
+	}
+
+	i := 0
+	err := c.elements(func() error {
+		defer func() { i++ }()
+
+		if rv.Kind() == reflect.Array {
+			if i >= rv.Len() {
+				return c.skip()
+			}
+			return c.decode(rv.Index(i))
+		}
+
+		rv.Set(reflect.Append(rv, reflect.Zero(rv.Type().Elem())))
+		return c.decode(rv.Index(i))
+	})
+
+	if rv.Kind() == reflect.Array {
+		for ; i < rv.Len(); i++ {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+		}
+	}
+	return err
+}
+
+func (c *Cursor) decodeAny() (interface{}, error) {
+	tok := c.curr()
+
+	switch tok.Kind {
+	case TokenLCB:
+		m := make(map[string]interface{})
+		err := c.members(func(key []byte, pos uint) error {
+			v, err := c.decodeAny()
+			m[string(key)] = v
+			return err
+		})
+		return m, err
+
+	case TokenLSB:
+		a := make([]interface{}, 0)
+		err := c.elements(func() error {
+			v, err := c.decodeAny()
+			a = append(a, v)
+			return err
+		})
+		return a, err
+
+	case TokenSTR:
+		s, err := Unescape(nil, tok.Load)
+		if err != nil {
+			return nil, &SyntaxError{Msg: err.Error(), Pos: tok.Pos}
+		}
+		c.next()
+		return string(s), nil
+
+	case TokenNUM:
+		f, err := strconv.ParseFloat(string(tok.Load), 64)
+		if err != nil {
+			return nil, &DecodeTypeError{Load: string(tok.Load), Type: reflect.TypeOf(f), Pos: tok.Pos}
+		}
+		c.next()
+		return f, nil
+
+	case TokenLIT:
+		c.next()
+		if tok.Load[0] == 'n' {
+			return nil, nil
+		}
+		return tok.Load[0] == 't', nil
+	}
+
+	if err := c.check(tok); err != nil {
+		return nil, err
+	}
+	return nil, c.unexpected(tok)
+}
+
+// members iterates over the members of the object under the
+// cursor, fn is invoked with the cursor pointing to the value.
+func (c *Cursor) members(fn func(key []byte, pos uint) error) error {
+	if tok := c.next(); tok.Is(TokenRCB) {
+		c.next()
+		return nil
+	}
+	for {
+		tok := c.curr()
+		if !tok.Is(TokenSTR) {
+			return c.unexpected(tok)
+		}
+		key, err := Unescape(nil, tok.Load)
+		if err != nil {
+			return &SyntaxError{Msg: err.Error(), Pos: tok.Pos}
+		}
+		pos := tok.Pos
+		if tok = c.next(); !tok.Is(TokenCOL) {
+			return c.unexpected(tok)
+		}
+		c.next()
+		if err = fn(key, pos); err != nil {
+			return err
+		}
+		switch tok = c.curr(); tok.Kind {
+		case TokenCOM:
+			c.next()
+		case TokenRCB:
+			c.next()
+			return nil
+		default:
+			return c.unexpected(tok)
+		}
+	}
+}
+
+// elements iterates over the elements of the array under the
+// cursor, fn is invoked with the cursor pointing to the element.
+func (c *Cursor) elements(fn func() error) error {
+	if tok := c.next(); tok.Is(TokenRSB) {
+		c.next()
+		return nil
+	}
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		switch tok := c.curr(); tok.Kind {
+		case TokenCOM:
+			c.next()
+		case TokenRSB:
+			c.next()
+			return nil
+		default:
+			return c.unexpected(tok)
+		}
+	}
+}
+
+// skip consumes the value under the cursor.
+func (c *Cursor) skip() error {
+	depth := 0
+	for {
+		tok := c.curr()
+		if err := c.check(tok); err != nil {
+			return err
+		}
+		switch tok.Kind {
+		case TokenLCB, TokenLSB:
+			depth++
+		case TokenRCB, TokenRSB:
+			depth--
+		}
+		c.next()
+		if depth <= 0 {
+			return nil
+		}
+	}
+}
+
+// curr returns the current Token of plain kind, see TokenKind.plain().
+func (c *Cursor) curr() Token {
+	tok := c.Curr()
+	tok.Kind = tok.Kind.plain()
+	return tok
+}
+
+// next advances the Cursor and returns the current Token of plain kind.
+func (c *Cursor) next() Token {
+	c.Next()
+	return c.curr()
+}
+
+func (c *Cursor) check(tok Token) error {
+	switch tok.Kind {
+	case TokenERR:
+		return c.err
+	case TokenEOF:
+		return &SyntaxError{Msg: "unexpected end of input", Pos: tok.Pos}
+	}
+	return nil
+}
+
+func (c *Cursor) unexpected(tok Token) error {
+	if err := c.check(tok); err != nil {
+		return err
+	}
+	return &SyntaxError{Msg: fmt.Sprintf("unexpected token %q", tok.Load), Pos: tok.Pos}
+}
+
+func (c *Cursor) typeError(rv reflect.Value) error {
+	tok := c.curr()
+	return &DecodeTypeError{Load: string(tok.Load), Type: rv.Type(), Pos: tok.Pos}
+}
+
+// indirect allocates nil pointers and returns the pointed to value.
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// fieldByIndex returns the (promoted) field, allocating embedded
+// pointers on the way. Like encoding/json, it fails on nil pointers
+// to unexported embedded structs, which can not be set.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for _, i := range index {
+		if rv.Kind() == reflect.Ptr && rv.IsNil() && !rv.CanSet() {
+			return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %s", rv.Type().Elem())
+		}
+		rv = indirect(rv).Field(i)
+	}
+	return rv, nil
+}
+
+type decodeField struct {
+	name   string
+	index  []int
+	tagged bool
+}
+
+var decodeFieldCache sync.Map // map[reflect.Type][]decodeField
+
+func decodeFields(t reflect.Type) []decodeField {
+	if f, ok := decodeFieldCache.Load(t); ok {
+		return f.([]decodeField)
+	}
+	f := dominantFields(collectFields(t, nil, map[reflect.Type]bool{}))
+	decodeFieldCache.Store(t, f)
+	return f
+}
+
+// collectFields gathers the fields of t and its embedded structs,
+// seen guards against recursive embedding.
+func collectFields(t reflect.Type, index []int, seen map[reflect.Type]bool) []decodeField {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var fields []decodeField
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := tag
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name = tag[:j]
+		}
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(ft, idx, seen)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // unexported
+		}
+		tagged := name != ""
+		if !tagged {
+			name = sf.Name
+		}
+		fields = append(fields, decodeField{name: name, index: idx, tagged: tagged})
+	}
+	return fields
+}
+
+// dominantFields applies the Go rules for promoted fields: of several
+// fields with the same name, the shallowest one wins, a tagged one breaks
+// a tie at the same depth. Other conflicting fields are dropped.
+func dominantFields(fields []decodeField) []decodeField {
+	sort.SliceStable(fields, func(i, j int) bool {
+		fi, fj := fields[i], fields[j]
+		if fi.name != fj.name {
+			return fi.name < fj.name
+		}
+		if len(fi.index) != len(fj.index) {
+			return len(fi.index) < len(fj.index)
+		}
+		return fi.tagged && !fj.tagged
+	})
+
+	out := fields[:0]
+	for i, j := 0, 0; i < len(fields); i = j {
+		for j = i + 1; j < len(fields) && fields[j].name == fields[i].name; j++ {
+		}
+		g := fields[i:j]
+		if len(g) > 1 && len(g[0].index) == len(g[1].index) && g[0].tagged == g[1].tagged {
+			continue
+		}
+		out = append(out, g[0])
+	}
+
+	// restore the order of declaration
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].index, out[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return out
+}
+
+func findField(fields []decodeField, key []byte) (decodeField, bool) {
+	for _, f := range fields {
+		if f.name == string(key) {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, string(key)) {
+			return f, true
+		}
+	}
+	return decodeField{}, false
+}
+
+var errDecodeNonPointer = errors.New("decode into non-pointer or nil value")
@@ -0,0 +1,238 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type decodeInner struct {
+	X int `json:"x"`
+}
+
+type decodeEmbedded struct {
+	E string
+}
+
+type decodeOuter struct {
+	decodeEmbedded
+	Name    string          `json:"name"`
+	Age     uint8           `json:"age,omitempty"`
+	Score   float64         `json:"score"`
+	Ok      bool            `json:"ok"`
+	Tags    []string        `json:"tags"`
+	Pair    [2]int          `json:"pair"`
+	Inner   *decodeInner    `json:"inner"`
+	Map     map[string]int  `json:"map"`
+	IntMap  map[int]bool    `json:"int_map"`
+	Any     interface{}     `json:"any"`
+	Raw     []byte          `json:"raw"`
+	When    time.Time       `json:"when"`
+	Skip    string          `json:"-"`
+	Nil     *decodeInner    `json:"nil"`
+	Nested  [][]decodeInner `json:"nested"`
+	private string
+	Objects map[string]string `json:"objects"`
+}
+
+func TestCursor_Decode_1(t *testing.T) {
+	s := `{
+		"name": "Jörg", "AGE": 42, "score": -1.5e2, "ok": true,
+		"tags": ["a", "b"], "pair": [1, 2, 3], "inner": {"x": 7, "y": [{}]},
+		"map": {"a": 1}, "int_map": {"-1": true}, "any": {"a": [1, "b", null, false]},
+		"raw": "AQID", "when": "2020-10-01T12:00:00Z", "Skip": "no", "nil": null,
+		"nested": [[{"x": 1}], []], "unknown": {"deep": [1, {"x": 2}]}, "E": "emb",
+		"objects": null
+	} 42`
+	c := NewCursor(bytes.NewReader([]byte(s)), nil)
+
+	v := decodeOuter{Nil: &decodeInner{}, Objects: map[string]string{}}
+	if err := c.Decode(&v); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	e := decodeOuter{
+		decodeEmbedded: decodeEmbedded{E: "emb"},
+		Name:           "Jörg",
+		Age:            42,
+		Score:          -150,
+		Ok:             true,
+		Tags:           []string{"a", "b"},
+		Pair:           [2]int{1, 2},
+		Inner:          &decodeInner{X: 7},
+		Map:            map[string]int{"a": 1},
+		IntMap:         map[int]bool{-1: true},
+		Any:            map[string]interface{}{"a": []interface{}{1.0, "b", nil, false}},
+		Raw:            []byte{1, 2, 3},
+		When:           time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+		Nested:         [][]decodeInner{{{X: 1}}, {}},
+	}
+	if !reflect.DeepEqual(v, e) {
+		t.Errorf("unexpected %+v", v)
+	}
+	if !c.Curr().Is(TokenNUM) {
+		t.Errorf("unexpected %q", c.Curr().Load)
+	}
+}
+
+// mix skipping with typed decoding
+func TestCursor_Decode_2(t *testing.T) {
+	s := `[{"x": 1}, {"x": 2}, {"x": 3}]`
+	c := NewCursor(bytes.NewReader([]byte(s)), nil)
+
+	var v []decodeInner
+	for c.Next(); !c.Curr().Is(TokenRSB); c.Next() {
+		var i decodeInner
+		if err := c.Decode(&i); err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		v = append(v, i)
+		if !c.Curr().Is(TokenCOM) {
+			break
+		}
+	}
+	if !reflect.DeepEqual(v, []decodeInner{{1}, {2}, {3}}) {
+		t.Errorf("unexpected %+v", v)
+	}
+}
+
+func TestCursor_Decode_3(t *testing.T) {
+	var (
+		i   int
+		u   uint
+		f   float32
+		s   string
+		b   bool
+		p   *int
+		a   interface{}
+		err error
+	)
+	dec := func(s string, v interface{}) error {
+		return NewCursor(bytes.NewReader([]byte(s)), nil).Decode(v)
+	}
+
+	if err = dec(`-7`, &i); err != nil || i != -7 {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`7`, &u); err != nil || u != 7 {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`0.5`, &f); err != nil || f != 0.5 {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`"a\tb"`, &s); err != nil || s != "a\tb" {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`false`, &b); err != nil || b {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`3`, &p); err != nil || *p != 3 {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`null`, &p); err != nil || p != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if err = dec(`[{}]`, &a); err != nil || !reflect.DeepEqual(a, []interface{}{map[string]interface{}{}}) {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+// expect errors
+func TestCursor_Decode_4(t *testing.T) {
+	var (
+		i int8
+		s string
+		m map[bool]int
+		v decodeInner
+	)
+	dec := func(s string, v interface{}) error {
+		return NewCursor(bytes.NewReader([]byte(s)), nil).Decode(v)
+	}
+	typeErr := &DecodeTypeError{}
+	syntaxErr := &SyntaxError{}
+
+	if err := dec(`1`, i); err != errDecodeNonPointer {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := dec(`300`, &i); !errors.As(err, &typeErr) {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := dec(`1`, &s); !errors.As(err, &typeErr) {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := dec(`{}`, &m); !errors.As(err, &typeErr) {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := dec(`[1]`, &v); !errors.As(err, &typeErr) {
+		t.Errorf("unexpected %v", err)
+	}
+	for _, in := range []string{``, `{"x" 1}`, `{"x": 1 "y": 2}`, `{"x": 1`, `[1 2]`, `{1: 1}`, `]`} {
+		var a interface{}
+		if err := dec(in, &a); !errors.As(err, &syntaxErr) {
+			t.Errorf("unexpected %q %v", in, err)
+		}
+	}
+	if err := NewCursor(&FaultyReader{}, nil).Decode(&s); err == nil {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestCursor_Decode_5(t *testing.T) {
+	s := `{"name": "x", "score": 1.5, "age": 7, "map": {"a": 1}}`
+	o := CursorOptLexer(LexerOptEmitKeys, LexerOptEmitNumberKinds)
+	c := NewCursor(bytes.NewReader([]byte(s)), nil, o)
+
+	var v decodeOuter
+	if err := c.Decode(&v); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if v.Name != "x" || v.Score != 1.5 || v.Age != 7 || v.Map["a"] != 1 {
+		t.Errorf("unexpected %+v", v)
+	}
+}
+
+type decodeHidden struct {
+	A int
+}
+
+type decodeShallow struct {
+	A string
+	X int
+}
+
+type decodeTagged struct {
+	X int `json:"X"`
+}
+
+type decodeDeep struct {
+	decodeShallow
+	decodeTagged
+}
+
+type decodePromoted struct {
+	*decodeHidden
+	decodeDeep
+	B int
+}
+
+func TestCursor_Decode_6(t *testing.T) {
+	c := NewCursor(bytes.NewReader([]byte(`{"A": 1, "B": 2}`)), nil)
+
+	var v decodePromoted
+	if err := c.Decode(&v); err == nil || err.Error() != "cannot set embedded pointer to unexported struct jsonlex.decodeHidden" {
+		t.Errorf("unexpected %v", err)
+	}
+
+	v = decodePromoted{decodeHidden: &decodeHidden{}}
+	c = NewCursor(bytes.NewReader([]byte(`{"A": 1, "B": 2, "X": 3}`)), nil)
+	if err := c.Decode(&v); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if v.decodeHidden.A != 1 || v.B != 2 || v.decodeShallow.A != "" || v.decodeTagged.X != 3 || v.decodeShallow.X != 0 {
+		t.Errorf("unexpected %+v", v)
+	}
+}
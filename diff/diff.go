@@ -0,0 +1,424 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package diff compares two JSON documents while running two
+// jsonlex.Cursors in lockstep. As long as the structure of the documents
+// is similar, neither of them is loaded into memory.
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/number"
+	"github.com/dtgorski/jsonlex/internal/pointer"
+)
+
+type (
+	// Difference describes a value which differs between the documents.
+	// When Order is set, the values are equal but the order of their
+	// members changed, Old and New list the member names present in
+	// both objects as JSON array.
+	Difference struct {
+		Pointer string // JSON Pointer (RFC 6901) to the value
+		Old     []byte // compact JSON text of the old value, nil if added
+		New     []byte // compact JSON text of the new value, nil if removed
+		Order   bool   // member order changed
+	}
+
+	differ struct {
+		diffs    []Difference
+		unorder  map[string]bool // pointers of objects with insignificant member order
+		unorders bool            // member order is insignificant for all objects
+	}
+)
+
+type diffOpt func(*differ)
+
+// OptIgnoreMemberOrder makes the member order insignificant for the objects
+// at the given JSON Pointers, or for all objects if no pointer is given.
+// By default, a changed order is reported as Difference with Order set.
+//
+// Members are compared by position as long as their names match. From the
+// first mismatch on, the remaining members of both objects are buffered
+// and matched by name.
+func OptIgnoreMemberOrder(pointers ...string) diffOpt {
+	return func(d *differ) {
+		if len(pointers) == 0 {
+			d.unorders = true
+		}
+		for _, p := range pointers {
+			d.unorder[p] = true
+		}
+	}
+}
+
+// Compare reads the old (from) and the new (to) document and
+// returns their differences in order of occurrence.
+func Compare(from, to io.Reader, opts ...diffOpt) ([]Difference, error) {
+	d := &differ{unorder: map[string]bool{}}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	a, b := jsonlex.NewCursor(from, nil), jsonlex.NewCursor(to, nil)
+	if err := d.value("", a, b); err != nil {
+		return nil, err
+	}
+	for _, c := range []*jsonlex.Cursor{a, b} {
+		if err := expect(c, jsonlex.TokenEOF); err != nil {
+			return nil, err
+		}
+	}
+	return d.diffs, nil
+}
+
+// value compares the values under the cursors and advances them past the values.
+func (d *differ) value(ptr string, a, b *jsonlex.Cursor) error {
+	ta, tb := a.Curr(), b.Curr()
+
+	switch {
+	case ta.Is(jsonlex.TokenLCB) && tb.Is(jsonlex.TokenLCB):
+		return d.object(ptr, a, b)
+	case ta.Is(jsonlex.TokenLSB) && tb.Is(jsonlex.TokenLSB):
+		return d.array(ptr, a, b)
+	}
+
+	ra, err := raw(a)
+	if err != nil {
+		return err
+	}
+	rb, err := raw(b)
+	if err != nil {
+		return err
+	}
+	if !equal(ta, tb) {
+		d.add(ptr, ra, rb)
+	}
+	return nil
+}
+
+func (d *differ) object(ptr string, a, b *jsonlex.Cursor) error {
+	a.Next()
+	b.Next()
+
+	for !a.Curr().Is(jsonlex.TokenRCB) && !b.Curr().Is(jsonlex.TokenRCB) {
+		ka, err := key(a)
+		if err != nil {
+			return err
+		}
+		kb, err := key(b)
+		if err != nil {
+			return err
+		}
+
+		if ka != kb {
+			return d.members(ptr, a, b, ka, kb)
+		}
+		if err = d.value(ptr+"/"+pointer.Escape(ka), a, b); err != nil {
+			return err
+		}
+		if err = separator(a, jsonlex.TokenRCB); err != nil {
+			return err
+		}
+		if err = separator(b, jsonlex.TokenRCB); err != nil {
+			return err
+		}
+	}
+
+	for !a.Curr().Is(jsonlex.TokenRCB) {
+		k, err := key(a)
+		if err == nil {
+			err = d.removed(ptr+"/"+pointer.Escape(k), a)
+		}
+		if err == nil {
+			err = separator(a, jsonlex.TokenRCB)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for !b.Curr().Is(jsonlex.TokenRCB) {
+		k, err := key(b)
+		if err == nil {
+			err = d.added(ptr+"/"+pointer.Escape(k), b)
+		}
+		if err == nil {
+			err = separator(b, jsonlex.TokenRCB)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	a.Next()
+	b.Next()
+	return nil
+}
+
+// members buffers the remaining members of both objects and matches
+// them by name. The cursors are located at the values of ka and kb.
+func (d *differ) members(ptr string, a, b *jsonlex.Cursor, ka, kb string) error {
+	ma, err := buffer(a, ka)
+	if err != nil {
+		return err
+	}
+	mb, err := buffer(b, kb)
+	if err != nil {
+		return err
+	}
+
+	if !d.unorders && !d.unorder[ptr] {
+		oa, ob := ma.common(mb), mb.common(ma)
+		for i := range oa {
+			if oa[i] != ob[i] {
+				d.diffs = append(d.diffs, Difference{Pointer: ptr, Old: names(oa), New: names(ob), Order: true})
+				break
+			}
+		}
+	}
+
+	for _, m := range ma.order {
+		p := ptr + "/" + pointer.Escape(m)
+		if rb, ok := mb.vals[m]; ok {
+			if err := d.compare(p, ma.vals[m], rb); err != nil {
+				return err
+			}
+		} else {
+			d.add(p, ma.vals[m], nil)
+		}
+	}
+	for _, m := range mb.order {
+		if _, ok := ma.vals[m]; !ok {
+			d.add(ptr+"/"+pointer.Escape(m), nil, mb.vals[m])
+		}
+	}
+	return nil
+}
+
+// compare compares two buffered values.
+func (d *differ) compare(ptr string, ra, rb []byte) error {
+	a := jsonlex.NewCursor(bytes.NewReader(ra), nil)
+	b := jsonlex.NewCursor(bytes.NewReader(rb), nil)
+	return d.value(ptr, a, b)
+}
+
+func (d *differ) array(ptr string, a, b *jsonlex.Cursor) error {
+	a.Next()
+	b.Next()
+
+	i := 0
+	for ; !a.Curr().Is(jsonlex.TokenRSB) && !b.Curr().Is(jsonlex.TokenRSB); i++ {
+		if err := d.value(ptr+"/"+strconv.Itoa(i), a, b); err != nil {
+			return err
+		}
+		if err := separator(a, jsonlex.TokenRSB); err != nil {
+			return err
+		}
+		if err := separator(b, jsonlex.TokenRSB); err != nil {
+			return err
+		}
+	}
+
+	for j := i; !a.Curr().Is(jsonlex.TokenRSB); j++ {
+		err := d.removed(ptr+"/"+strconv.Itoa(j), a)
+		if err == nil {
+			err = separator(a, jsonlex.TokenRSB)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for j := i; !b.Curr().Is(jsonlex.TokenRSB); j++ {
+		err := d.added(ptr+"/"+strconv.Itoa(j), b)
+		if err == nil {
+			err = separator(b, jsonlex.TokenRSB)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	a.Next()
+	b.Next()
+	return nil
+}
+
+func (d *differ) removed(ptr string, c *jsonlex.Cursor) error {
+	r, err := raw(c)
+	if err == nil {
+		d.add(ptr, r, nil)
+	}
+	return err
+}
+
+func (d *differ) added(ptr string, c *jsonlex.Cursor) error {
+	r, err := raw(c)
+	if err == nil {
+		d.add(ptr, nil, r)
+	}
+	return err
+}
+
+func (d *differ) add(ptr string, from, to []byte) {
+	d.diffs = append(d.diffs, Difference{Pointer: ptr, Old: from, New: to})
+}
+
+type members struct {
+	order []string
+	vals  map[string][]byte
+}
+
+// common returns the names of m which are present in o.
+func (m members) common(o members) []string {
+	var c []string
+	for _, k := range m.order {
+		if _, ok := o.vals[k]; ok {
+			c = append(c, k)
+		}
+	}
+	return c
+}
+
+// names returns the member names as JSON array.
+func names(keys []string) []byte {
+	b := []byte{'['}
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(jsonlex.Escape(append(b, '"'), k), '"')
+	}
+	return append(b, ']')
+}
+
+// buffer reads the remaining members of an object, the
+// cursor is located at the value of the member k.
+func buffer(c *jsonlex.Cursor, k string) (members, error) {
+	m := members{vals: map[string][]byte{}}
+	for {
+		r, err := raw(c)
+		if err != nil {
+			return m, err
+		}
+		if _, ok := m.vals[k]; !ok {
+			m.order = append(m.order, k)
+		}
+		m.vals[k] = r // last wins
+
+		if err = separator(c, jsonlex.TokenRCB); err != nil {
+			return m, err
+		}
+		if c.Curr().Is(jsonlex.TokenRCB) {
+			c.Next()
+			return m, nil
+		}
+		if k, err = key(c); err != nil {
+			return m, err
+		}
+	}
+}
+
+// key reads a member name and the colon.
+func key(c *jsonlex.Cursor) (string, error) {
+	tok := c.Curr()
+	if err := expect(c, jsonlex.TokenSTR); err != nil {
+		return "", err
+	}
+	k, err := jsonlex.Unescape(nil, tok.Load)
+	if err != nil {
+		return "", &jsonlex.SyntaxError{Msg: err.Error(), Pos: tok.Pos}
+	}
+	if err = expect(c, jsonlex.TokenCOL); err != nil {
+		return "", err
+	}
+	return string(k), nil
+}
+
+// separator consumes a comma, unless the container ends.
+func separator(c *jsonlex.Cursor, end jsonlex.TokenKind) error {
+	if c.Curr().Is(end) {
+		return nil
+	}
+	if err := expect(c, jsonlex.TokenCOM); err != nil {
+		return err
+	}
+	if c.Curr().Is(end) {
+		return unexpected(c)
+	}
+	return nil
+}
+
+// raw consumes a value and returns its compact JSON text.
+func raw(c *jsonlex.Cursor) ([]byte, error) {
+	var b []byte
+	for depth := 0; ; {
+		tok := c.Curr()
+		switch tok.Kind {
+		case jsonlex.TokenLCB, jsonlex.TokenLSB:
+			depth++
+		case jsonlex.TokenRCB, jsonlex.TokenRSB:
+			depth--
+		case jsonlex.TokenSTR, jsonlex.TokenNUM, jsonlex.TokenLIT:
+		case jsonlex.TokenCOL, jsonlex.TokenCOM:
+			if depth > 0 {
+				break
+			}
+			fallthrough
+		default:
+			return nil, unexpected(c)
+		}
+		if depth < 0 {
+			return nil, unexpected(c)
+		}
+
+		if tok.Is(jsonlex.TokenSTR) {
+			b = append(append(append(b, '"'), tok.Load...), '"')
+		} else {
+			b = append(b, tok.Load...)
+		}
+		if c.Next(); depth == 0 {
+			return b, nil
+		}
+	}
+}
+
+// equal compares two scalar values.
+func equal(a, b jsonlex.Token) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case jsonlex.TokenNUM:
+		return bytes.Equal(a.Load, b.Load) || number.Normal(a.Load) == number.Normal(b.Load)
+	case jsonlex.TokenSTR:
+		if bytes.Equal(a.Load, b.Load) {
+			return true
+		}
+		ua, ea := jsonlex.Unescape(nil, a.Load)
+		ub, eb := jsonlex.Unescape(nil, b.Load)
+		return ea == nil && eb == nil && bytes.Equal(ua, ub)
+	}
+	return bytes.Equal(a.Load, b.Load)
+}
+
+func expect(c *jsonlex.Cursor, kind jsonlex.TokenKind) error {
+	if !c.Curr().Is(kind) {
+		return unexpected(c)
+	}
+	c.Next()
+	return nil
+}
+
+func unexpected(c *jsonlex.Cursor) error {
+	tok := c.Curr()
+	switch {
+	case tok.Is(jsonlex.TokenERR):
+		return c.Err()
+	case tok.Is(jsonlex.TokenEOF):
+		return &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: tok.Pos}
+	}
+	return &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", tok.Load), Pos: tok.Pos}
+}
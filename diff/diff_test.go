@@ -0,0 +1,94 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func compare(t *testing.T, a, b string, opts ...diffOpt) string {
+	diffs, err := Compare(strings.NewReader(a), strings.NewReader(b), opts...)
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	var r []string
+	for _, d := range diffs {
+		if d.Order {
+			r = append(r, fmt.Sprintf("%s order %s>%s", d.Pointer, d.Old, d.New))
+		} else {
+			r = append(r, fmt.Sprintf("%s %s>%s", d.Pointer, d.Old, d.New))
+		}
+	}
+	return strings.Join(r, " | ")
+}
+
+func TestCompare_1(t *testing.T) {
+	a := `{"a": 1, "b": [1, 2, {"c": "x"}], "d": {"e": null}, "f": "A", "g": 1.0}`
+	b := `{"a": 2, "b": [1, 3, {"c": "y"}, 4], "d": [], "f": "A", "g": 1e0, "h": true}`
+	e := `/a 1>2 | /b/1 2>3 | /b/2/c "x">"y" | /b/3 >4 | /d {"e":null}>[] | /h >true`
+
+	if d := compare(t, a, b); d != e {
+		t.Errorf("unexpected %s", d)
+	}
+	if d := compare(t, a, a); d != "" {
+		t.Errorf("unexpected %s", d)
+	}
+}
+
+func TestCompare_2(t *testing.T) {
+	a := `{"x": {"a": 1, "b": {"c": 2}, "d/e": 3}, "y": {"p": 1, "q": 2}}`
+	b := `{"x": {"a": 1, "d/e": 3, "b": {"c": 5}, "z": 0}, "y": {"q": 2, "p": 1}}`
+
+	e := `/x order ["b","d/e"]>["d/e","b"] | /x/b/c 2>5 | /x/z >0 | /y order ["p","q"]>["q","p"]`
+	if d := compare(t, a, b); d != e {
+		t.Errorf("unexpected %s", d)
+	}
+
+	e = `/x/b/c 2>5 | /x/z >0`
+	if d := compare(t, a, b, OptIgnoreMemberOrder()); d != e {
+		t.Errorf("unexpected %s", d)
+	}
+
+	e = `/x/b/c 2>5 | /x/z >0 | /y order ["p","q"]>["q","p"]`
+	if d := compare(t, a, b, OptIgnoreMemberOrder("/x")); d != e {
+		t.Errorf("unexpected %s", d)
+	}
+}
+
+func TestCompare_3(t *testing.T) {
+	if d := compare(t, `[1, 2, 3]`, `[1]`); d != "/1 2> | /2 3>" {
+		t.Errorf("unexpected %s", d)
+	}
+	if d := compare(t, `"a"`, `{"a": [true]}`); d != ` "a">{"a":[true]}` {
+		t.Errorf("unexpected %s", d)
+	}
+	if d := compare(t, `{"a": {}}`, `{}`, OptIgnoreMemberOrder()); d != "/a {}>" {
+		t.Errorf("unexpected %s", d)
+	}
+}
+
+func TestCompare_4(t *testing.T) {
+	for _, s := range [][2]string{
+		{`[1,]`, `[1]`}, {`{"a" 1}`, `{}`}, {`[}`, `[]`}, {`1 2`, `1`},
+		{`{"a": 1`, `{"a": 1}`}, {`{"a": 1, }`, `{"b": 1}`}, {`@`, `1`}, {`[`, `[1]`},
+	} {
+		if _, err := Compare(strings.NewReader(s[0]), strings.NewReader(s[1])); err == nil {
+			t.Errorf("unexpected nil error %s", s[0])
+		}
+		if _, err := Compare(strings.NewReader(s[1]), strings.NewReader(s[0]), OptIgnoreMemberOrder()); err == nil {
+			t.Errorf("unexpected nil error %s", s[0])
+		}
+	}
+}
+
+func TestCompare_5(t *testing.T) {
+	a := `[9007199254740993, 1.50, -0.0, 12E+30, 1e400, {"a": 1, "b": 2, "c": 3}]`
+	b := `[9007199254740992, 15e-1, 0, 1.2e31, 1e400, {"b": 2, "a": 1, "c": 4}]`
+	e := `/0 9007199254740993>9007199254740992 | /5 order ["a","b","c"]>["b","a","c"] | /5/c 3>4`
+
+	if d := compare(t, a, b); d != e {
+		t.Errorf("unexpected %s", d)
+	}
+}
@@ -0,0 +1,132 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package dom
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+// builder appends nodes in document order, the
+// structure of the token stream is validated by g.
+type builder struct {
+	doc   *document
+	g     jsonlex.Grammar
+	open  []int32 // indices of open containers
+	kids  []int32 // indices of children of open containers
+	marks []int   // start of the children of open containers in kids
+	koff  uint32  // pending member name
+	klen  uint32
+	buff  []byte // scratch for escape validation
+	err   error
+}
+
+func (b *builder) yield(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+	switch kind {
+	case jsonlex.TokenERR:
+		return false
+
+	case jsonlex.TokenEOF:
+		if !b.g.Done() || len(b.doc.nodes) == 0 {
+			b.err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: pos}
+		}
+		return false
+	}
+
+	if len(b.open) == 0 && len(b.doc.nodes) > 0 {
+		return b.fail(load, pos) // exactly one value
+	}
+	key, ok := b.g.Push(kind)
+	if !ok {
+		return b.fail(load, pos)
+	}
+
+	switch kind {
+	case jsonlex.TokenSTR:
+		if !b.validate(load, pos) {
+			return false
+		}
+		if key {
+			b.koff, b.klen = uint32(pos)+1, uint32(len(load))
+			return true
+		}
+		b.value(String, pos, uint32(len(load))+2)
+
+	case jsonlex.TokenNUM:
+		b.value(Number, pos, uint32(len(load)))
+
+	case jsonlex.TokenLIT:
+		if load[0] == 'n' {
+			b.value(Null, pos, uint32(len(load)))
+		} else {
+			b.value(Bool, pos, uint32(len(load)))
+		}
+
+	case jsonlex.TokenLCB:
+		b.value(Object, pos, 0)
+
+	case jsonlex.TokenLSB:
+		b.value(Array, pos, 0)
+
+	case jsonlex.TokenRCB, jsonlex.TokenRSB:
+		b.close(pos)
+	}
+	return true
+}
+
+func (b *builder) value(kind Kind, pos uint, n uint32) {
+	idx := int32(len(b.doc.nodes))
+	b.doc.nodes = append(b.doc.nodes, node{
+		kind: kind, off: uint32(pos), len: n,
+	})
+
+	if len(b.open) > 0 {
+		b.kids = append(b.kids, idx)
+		p := b.top()
+		if p.kind == Object {
+			b.doc.nodes[idx].koff, b.doc.nodes[idx].klen = b.koff, b.klen
+		}
+		p.size++
+	}
+
+	if kind == Object || kind == Array {
+		b.open, b.marks = append(b.open, idx), append(b.marks, len(b.kids))
+	}
+}
+
+func (b *builder) close(pos uint) {
+	d := len(b.open)
+	n := b.top()
+	n.len = uint32(pos) + 1 - n.off
+	n.kids = int32(len(b.doc.kids))
+	b.doc.kids = append(b.doc.kids, b.kids[b.marks[d-1]:]...)
+	b.kids = b.kids[:b.marks[d-1]]
+	b.open, b.marks = b.open[:d-1], b.marks[:d-1]
+}
+
+func (b *builder) top() *node {
+	return &b.doc.nodes[b.open[len(b.open)-1]]
+}
+
+func (b *builder) validate(load []byte, pos uint) bool {
+	if int(pos)+len(load)+2 > len(b.doc.data) {
+		b.err = &jsonlex.SyntaxError{Msg: "unterminated string", Pos: pos}
+		return false
+	}
+	if bytes.IndexByte(load, '\\') < 0 {
+		return true
+	}
+	var err error
+	if b.buff, err = jsonlex.Unescape(b.buff[:0], load); err != nil {
+		b.err = &jsonlex.SyntaxError{Msg: err.Error(), Pos: pos}
+		return false
+	}
+	return true
+}
+
+func (b *builder) fail(load []byte, pos uint) bool {
+	b.err = &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+	return false
+}
@@ -0,0 +1,222 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package dom builds a compact, read-only value tree from the output
+// of the jsonlex.Lexer. The tree references the bytes of the input
+// buffer, strings and numbers are decoded lazily on access.
+package dom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+type (
+	// Value is a node in the tree. The zero Value is
+	// of Invalid kind and denotes a failed lookup.
+	Value struct {
+		doc *document
+		idx int32
+	}
+
+	// Kind denotes the type of a Value.
+	Kind uint8
+
+	document struct {
+		data  []byte
+		nodes []node
+		kids  []int32 // indices of children, see node.kids
+	}
+
+	node struct {
+		kind Kind
+		off  uint32 // offset of value in data
+		len  uint32 // length of value in data
+		koff uint32 // offset of member name in data
+		klen uint32 // length of member name in data
+		size int32  // number of children
+		kids int32  // offset of the children indices in kids
+	}
+)
+
+// Kinds of values.
+const (
+	Invalid Kind = iota // zero Value
+	Null                // null
+	Bool                // true, false
+	Number              // float number
+	String              // "...\"..."
+	Object              // {...}
+	Array               // [...]
+)
+
+// Parse builds the tree from a buffer which must contain exactly one JSON
+// value. The buffer is referenced by the tree and must not be modified.
+func Parse(data []byte) (Value, error) {
+	if uint64(len(data)) > math.MaxUint32 {
+		return Value{}, errTooLarge
+	}
+	b := &builder{
+		doc: &document{data: data, nodes: make([]node, 0, len(data)/8+1)},
+	}
+	l := jsonlex.NewLexer(b.yield)
+	l.Scan(bytes.NewReader(data))
+
+	if l.Err() != nil {
+		return Value{}, l.Err()
+	}
+	if b.err != nil {
+		return Value{}, b.err
+	}
+	return Value{doc: b.doc}, nil
+}
+
+// Kind returns the kind of value.
+func (v Value) Kind() Kind {
+	if v.doc == nil {
+		return Invalid
+	}
+	return v.node().kind
+}
+
+// Raw returns the JSON text of the value (strings enclosed in
+// quotation marks). The returned slice references the input buffer.
+func (v Value) Raw() []byte {
+	if v.doc == nil {
+		return nil
+	}
+	n := v.node()
+	return v.doc.data[n.off : n.off+n.len]
+}
+
+// Len returns the number of object members or array elements.
+func (v Value) Len() int {
+	if v.doc == nil {
+		return 0
+	}
+	return int(v.node().size)
+}
+
+// Key returns the unescaped member name when the
+// Value is an object member, an empty string otherwise.
+func (v Value) Key() string {
+	if v.doc == nil {
+		return ""
+	}
+	n := v.node()
+	return unescape(v.doc.data[n.koff : n.koff+n.klen])
+}
+
+// Get returns the member of an object with the given name. If the
+// name occurs more than once, the last member wins. A Value of Invalid
+// kind is returned, if there is no such member.
+func (v Value) Get(name string) Value {
+	var found Value
+	if v.Kind() != Object {
+		return found
+	}
+	v.ForEach(func(_ int, m Value) bool {
+		n := m.node()
+		k := v.doc.data[n.koff : n.koff+n.klen]
+		if string(k) == name || bytes.IndexByte(k, '\\') >= 0 && unescape(k) == name {
+			found = m
+		}
+		return true
+	})
+	return found
+}
+
+// Index returns the i-th element of an array or the i-th member
+// of an object in constant time. A Value of Invalid kind is
+// returned, if there is no such element.
+func (v Value) Index(i int) Value {
+	if i < 0 || i >= v.Len() {
+		return Value{}
+	}
+	return Value{doc: v.doc, idx: v.doc.kids[int(v.node().kids)+i]}
+}
+
+// ForEach invokes fn for each array element or object member in
+// order of occurrence, until fn returns false.
+func (v Value) ForEach(fn func(i int, v Value) bool) {
+	if k := v.Kind(); k != Object && k != Array {
+		return
+	}
+	n := v.node()
+	for i, j := range v.doc.kids[n.kids : n.kids+n.size] {
+		if !fn(i, Value{doc: v.doc, idx: j}) {
+			return
+		}
+	}
+}
+
+// String returns the unescaped string of a String value, the
+// JSON text otherwise.
+func (v Value) String() string {
+	if v.Kind() == String {
+		raw := v.Raw()
+		return unescape(raw[1 : len(raw)-1])
+	}
+	return string(v.Raw())
+}
+
+// Bool returns true for a true literal, false otherwise.
+func (v Value) Bool() bool {
+	return v.Kind() == Bool && v.Raw()[0] == 't'
+}
+
+// Float returns the number as float64.
+func (v Value) Float() (float64, error) {
+	if v.Kind() != Number {
+		return 0, v.kindError(Number)
+	}
+	return strconv.ParseFloat(string(v.Raw()), 64)
+}
+
+// Int returns the number as int64.
+func (v Value) Int() (int64, error) {
+	if v.Kind() != Number {
+		return 0, v.kindError(Number)
+	}
+	return strconv.ParseInt(string(v.Raw()), 10, 64)
+}
+
+func (v Value) node() *node {
+	return &v.doc.nodes[v.idx]
+}
+
+func (v Value) kindError(k Kind) error {
+	return fmt.Errorf("value of kind %s is not %s", v.Kind(), k)
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Null:
+		return "null"
+	case Bool:
+		return "bool"
+	case Number:
+		return "number"
+	case String:
+		return "string"
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	}
+	return "invalid"
+}
+
+func unescape(load []byte) string {
+	if bytes.IndexByte(load, '\\') < 0 {
+		return string(load)
+	}
+	s, _ := jsonlex.Unescape(nil, load) // validated while parsing
+	return string(s)
+}
+
+var errTooLarge = errors.New("input exceeds 4 GiB")
@@ -0,0 +1,123 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package dom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func TestParse_1(t *testing.T) {
+	s := ` { "foo": "b\"ar", "baz": [ null, true, false, -42, {"x": [1]}, [] ], "n": 0.5 } `
+
+	v, err := Parse([]byte(s))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if v.Kind() != Object || v.Len() != 3 {
+		t.Errorf("unexpected %s %d", v.Kind(), v.Len())
+	}
+	if f := v.Get("foo"); f.Kind() != String || f.String() != `b"ar` || string(f.Raw()) != `"b\"ar"` {
+		t.Errorf("unexpected %q", f.Raw())
+	}
+
+	a := v.Get("baz")
+	if a.Kind() != Array || a.Len() != 6 || a.Key() != "baz" {
+		t.Errorf("unexpected %q", a.Raw())
+	}
+	if string(a.Raw()) != `[ null, true, false, -42, {"x": [1]}, [] ]` {
+		t.Errorf("unexpected %q", a.Raw())
+	}
+	if a.Index(0).Kind() != Null || !a.Index(1).Bool() || a.Index(2).Bool() {
+		t.Errorf("unexpected")
+	}
+	if n, err := a.Index(3).Int(); err != nil || n != -42 {
+		t.Errorf("unexpected %d %v", n, err)
+	}
+	if x := a.Index(4).Get("x").Index(0); x.String() != "1" {
+		t.Errorf("unexpected %q", x.Raw())
+	}
+	if e := a.Index(5); e.Kind() != Array || e.Len() != 0 {
+		t.Errorf("unexpected %q", e.Raw())
+	}
+	if f, err := v.Get("n").Float(); err != nil || f != 0.5 {
+		t.Errorf("unexpected %f %v", f, err)
+	}
+	if m := v.Index(2); m.Key() != "n" {
+		t.Errorf("unexpected %q", m.Key())
+	}
+}
+
+// expect Invalid values on failed lookups
+func TestParse_2(t *testing.T) {
+	v, err := Parse([]byte(`{"a": [1], "a": [2, 3]}`))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if v.Get("a").Len() != 2 {
+		t.Errorf("unexpected")
+	}
+	if v.Get("b").Kind() != Invalid || v.Index(2).Kind() != Invalid || v.Index(-1).Kind() != Invalid {
+		t.Errorf("unexpected")
+	}
+	if x := v.Get("b").Get("c").Index(0); x.Kind() != Invalid || x.Raw() != nil || x.Len() != 0 {
+		t.Errorf("unexpected")
+	}
+	if _, err := v.Float(); err == nil {
+		t.Errorf("unexpected")
+	}
+	if _, err := v.Int(); err == nil {
+		t.Errorf("unexpected")
+	}
+
+	i := 0
+	v.ForEach(func(_ int, m Value) bool {
+		i++
+		return false
+	})
+	if i != 1 {
+		t.Errorf("unexpected")
+	}
+}
+
+// expect syntax errors
+func TestParse_3(t *testing.T) {
+	s := []string{
+		``, ` `, `{`, `[`, `]`, `}`, `{]`, `[}`, `,`, `:`, `1 2`, `{} []`,
+		`{"a"}`, `{"a":}`, `{"a" 1}`, `{1:1}`, `{"a":1,}`, `"abc`,
+		`[1,]`, `[1 2]`, `[,1]`, `{"a":1 "b":2}`, `[1:2]`, `["\x"]`,
+	}
+	for _, v := range s {
+		e := &jsonlex.SyntaxError{}
+		if _, err := Parse([]byte(v)); !errors.As(err, &e) {
+			t.Errorf("unexpected %q %v", v, err)
+		}
+	}
+	if _, err := Parse([]byte(`[*]`)); err == nil {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestKind_String(t *testing.T) {
+	if Object.String() != "object" || Kind(99).String() != "invalid" {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestValue_Index_1(t *testing.T) {
+	v, err := Parse([]byte(`[[0, [1]], {"a": {"b": 2}, "c": 3}, 4]`))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if s := v.Index(0).Index(1).Index(0).String(); s != "1" {
+		t.Errorf("unexpected %s", s)
+	}
+	if m := v.Index(1).Index(1); m.Key() != "c" || m.String() != "3" {
+		t.Errorf("unexpected %s", m.Raw())
+	}
+	if s := v.Index(2).String(); s != "4" || v.Index(3).Kind() != Invalid || v.Index(-1).Kind() != Invalid {
+		t.Errorf("unexpected %s", s)
+	}
+}
@@ -0,0 +1,89 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// DuplicateKeyError describes a member name occurring
+// more than once in the same object.
+type DuplicateKeyError struct {
+	Key   string // unescaped member name
+	First uint   // position of the first occurrence
+	Pos   uint   // position of the duplicate
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %q at %d, first at %d", e.Key, e.Pos, e.First)
+}
+
+var (
+	// LexerOptRejectDuplicateKeys enables the detection of duplicate
+	// member names. A duplicate is not yielded, a jsonlex.TokenERR is
+	// emitted instead and Lexer.Err() returns a *DuplicateKeyError.
+	// Member names are kept as 64 bit hashes per object, seeded randomly
+	// per Lexer, so that colliding names cannot be crafted. Two distinct
+	// names may still be reported as duplicate with a probability of
+	// about n²/2⁶⁵ for n names in an object. The names of objects opened
+	// before a Restore() are not known to the Lexer.
+	LexerOptRejectDuplicateKeys lexerOpt = func(l *Lexer) {
+		l.dupe, l.track = true, true
+	}
+)
+
+// LexerOptWarnDuplicateKeys enables the detection of duplicate member
+// names like LexerOptRejectDuplicateKeys does, but the given callback
+// is invoked for each duplicate and the scan process continues.
+func LexerOptWarnDuplicateKeys(fn func(err *DuplicateKeyError)) lexerOpt {
+	return func(l *Lexer) {
+		l.warn, l.track = fn, true
+	}
+}
+
+// openNames prepares the member names of an object to be opened.
+func (l *Lexer) openNames() {
+	if d := len(l.nest); d < len(l.names) {
+		for h := range l.names[d] {
+			delete(l.names[d], h)
+		}
+	}
+}
+
+// checkName records a member name and reports whether to continue.
+func (l *Lexer) checkName(load []byte, pos uint) bool {
+	d := len(l.nest) - 1
+	for len(l.names) <= d {
+		l.names = append(l.names, nil)
+	}
+	if l.names[d] == nil {
+		l.names[d] = make(map[uint64]uint)
+	}
+
+	name := load
+	if bytes.IndexByte(load, '\\') >= 0 {
+		if u, err := Unescape(l.ubuf[:0], load); err == nil {
+			l.ubuf, name = u, u
+		}
+	}
+
+	l.hash.Reset()
+	_, _ = l.hash.Write(name)
+	h := l.hash.Sum64()
+
+	first, ok := l.names[d][h]
+	if !ok {
+		l.names[d][h] = pos
+		return true
+	}
+
+	err := &DuplicateKeyError{Key: string(name), First: first, Pos: pos}
+	if !l.dupe {
+		l.warn(err)
+		return true
+	}
+	l.err = err
+	l.yield(TokenERR, []byte(err.Error()), pos)
+	return false
+}
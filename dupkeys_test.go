@@ -0,0 +1,69 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLexer_RejectDuplicateKeys_1(t *testing.T) {
+	s := `{"a": {"a": 1, "b": [{"a": 2}, {"a": 3}]}, "c": 4, "a": 5}`
+
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, string(load))
+		return true
+	}
+	l := NewLexer(y, LexerOptRejectDuplicateKeys)
+	l.Scan(bytes.NewReader([]byte(s)))
+
+	err, ok := l.Err().(*DuplicateKeyError)
+	if !ok || err.Key != "a" || err.First != 1 || err.Pos != 51 {
+		t.Fatalf("unexpected %v", l.Err())
+	}
+	if toks[len(toks)-1] != `duplicate key "a" at 51, first at 1` || toks[len(toks)-2] != "," {
+		t.Errorf("unexpected %v", toks)
+	}
+}
+
+func TestLexer_RejectDuplicateKeys_2(t *testing.T) {
+	s := `{"a": {"b": 1}, "b": [{"a": 1, "b": 2}], "c": {"a": {}}}`
+
+	for _, scan := range []func(l *Lexer){
+		func(l *Lexer) { l.Scan(bytes.NewReader([]byte(s))) },
+		func(l *Lexer) { l.ScanIndexed([]byte(s)) },
+		func(l *Lexer) { l.ScanParallel([]byte(s), 2) },
+	} {
+		var last TokenKind
+		l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+			last = kind
+			return true
+		}, LexerOptRejectDuplicateKeys)
+		if scan(l); l.Err() != nil || !last.Is(TokenEOF) {
+			t.Errorf("unexpected %v", l.Err())
+		}
+	}
+}
+
+func TestLexer_WarnDuplicateKeys_1(t *testing.T) {
+	s := `[{"a": 1, "b": 2, "a": 3, "b": 4, "a": 5}, {"a": 6}]`
+
+	var warns []string
+	w := LexerOptWarnDuplicateKeys(func(err *DuplicateKeyError) {
+		warns = append(warns, fmt.Sprintf("%s:%d:%d", err.Key, err.First, err.Pos))
+	})
+
+	n := 0
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		n++
+		return true
+	}, w, LexerOptEmitKeys)
+	l.Scan(bytes.NewReader([]byte(s)))
+
+	if strings.Join(warns, " ") != "a:2:18 b:10:26 a:2:34" || n != 30 || l.Err() != nil {
+		t.Errorf("unexpected %v %d", warns, n)
+	}
+}
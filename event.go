@@ -0,0 +1,128 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"fmt"
+	"io"
+)
+
+type (
+	// Handler receives the events emitted by the EventDecoder.
+	// Returning an error stops the decoding process, the error
+	// will be handed over to the caller of EventDecoder.Decode().
+	// The byte slices passed are only valid during the call.
+	Handler interface {
+		StartObject() error
+		Key(key []byte) error
+		EndObject() error
+		StartArray() error
+		EndArray() error
+		String(str []byte) error
+		Number(num []byte) error
+		Bool(b bool) error
+		Null() error
+	}
+
+	// EventDecoder feeds the Lexer output into a Handler
+	// (SAX-style). Object member names and values are
+	// distinguished, the structure of the input is validated.
+	EventDecoder struct {
+		handler  Handler
+		grammar  Grammar
+		unescape bool
+		buff     []byte
+	}
+)
+
+// NewEventDecoder creates an EventDecoder for the given Handler.
+func NewEventDecoder(h Handler, opts ...eventDecoderOpt) *EventDecoder {
+	d := &EventDecoder{
+		handler: h,
+		buff:    make([]byte, 0, 1024),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+type eventDecoderOpt func(*EventDecoder)
+
+var (
+	// EventDecoderOptUnescape enables unescaping of member names and
+	// string values before they are passed to Handler.Key() and
+	// Handler.String(). By default, the raw string load is passed.
+	EventDecoderOptUnescape eventDecoderOpt = func(d *EventDecoder) {
+		d.unescape = true
+	}
+)
+
+// Decode consumes the byte stream until its end and invokes the
+// Handler for each event. A stream of concatenated top level
+// values is accepted. Malformed input results in an error.
+func (d *EventDecoder) Decode(r io.Reader) error {
+	var err error
+	d.grammar.Reset()
+
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenEOF) {
+			if !d.grammar.Done() {
+				err = &SyntaxError{Msg: "unexpected end of input", Pos: pos}
+			}
+			return false
+		}
+		if kind.Is(TokenERR) {
+			return false
+		}
+		key, ok := d.grammar.Push(kind)
+		if !ok {
+			err = &SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+			return false
+		}
+		err = d.dispatch(kind, load, pos, key)
+		return err == nil
+	})
+
+	l.Scan(r)
+
+	if l.Err() != nil {
+		return l.Err()
+	}
+	return err
+}
+
+func (d *EventDecoder) dispatch(kind TokenKind, load []byte, pos uint, key bool) error {
+	h := d.handler
+
+	switch kind {
+	case TokenLCB:
+		return h.StartObject()
+	case TokenRCB:
+		return h.EndObject()
+	case TokenLSB:
+		return h.StartArray()
+	case TokenRSB:
+		return h.EndArray()
+	case TokenNUM:
+		return h.Number(load)
+	case TokenLIT:
+		if load[0] == 'n' {
+			return h.Null()
+		}
+		return h.Bool(load[0] == 't')
+	case TokenSTR:
+		if d.unescape {
+			var err error
+			if d.buff, err = Unescape(d.buff[:0], load); err != nil {
+				return &SyntaxError{Msg: err.Error(), Pos: pos}
+			}
+			load = d.buff
+		}
+		if key {
+			return h.Key(load)
+		}
+		return h.String(load)
+	}
+	return nil
+}
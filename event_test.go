@@ -0,0 +1,109 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type recordingHandler struct {
+	events []string
+	failAt int
+}
+
+func (h *recordingHandler) add(s string) error {
+	h.events = append(h.events, s)
+	if len(h.events) == h.failAt {
+		return errors.New("fail")
+	}
+	return nil
+}
+
+func (h *recordingHandler) StartObject() error      { return h.add("{") }
+func (h *recordingHandler) Key(key []byte) error    { return h.add("K:" + string(key)) }
+func (h *recordingHandler) EndObject() error        { return h.add("}") }
+func (h *recordingHandler) StartArray() error       { return h.add("[") }
+func (h *recordingHandler) EndArray() error         { return h.add("]") }
+func (h *recordingHandler) String(str []byte) error { return h.add("S:" + string(str)) }
+func (h *recordingHandler) Number(num []byte) error { return h.add("N:" + string(num)) }
+func (h *recordingHandler) Bool(b bool) error       { return h.add(fmt.Sprint("B:", b)) }
+func (h *recordingHandler) Null() error             { return h.add("null") }
+
+func TestEventDecoder_1(t *testing.T) {
+	s := ` { "foo": "bar", "b\naz": [ null, true, false, -42, {}, [] ] } "x" 1 `
+	e := `{ K:foo S:bar K:b\naz [ null B:true B:false N:-42 { } [ ] ] } S:x N:1`
+
+	h := &recordingHandler{}
+	d := NewEventDecoder(h)
+	r := bytes.NewReader([]byte(s))
+
+	if err := d.Decode(r); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if v := strings.Join(h.events, " "); v != e {
+		t.Errorf("unexpected %s", v)
+	}
+}
+
+func TestEventDecoder_2(t *testing.T) {
+	s := `{"föo": "b\"ar"}`
+	e := "{ K:föo S:b\"ar }"
+
+	h := &recordingHandler{}
+	d := NewEventDecoder(h, EventDecoderOptUnescape)
+	r := bytes.NewReader([]byte(s))
+
+	if err := d.Decode(r); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if v := strings.Join(h.events, " "); v != e {
+		t.Errorf("unexpected %s", v)
+	}
+}
+
+// expect syntax errors
+func TestEventDecoder_3(t *testing.T) {
+	s := []string{
+		`{`, `[`, `]`, `}`, `{]`, `[}`, `,`, `:`,
+		`{"a"}`, `{"a":}`, `{"a" 1}`, `{1:1}`, `{"a":1,}`,
+		`[1,]`, `[1 2]`, `[,1]`, `{"a":1 "b":2}`, `[1:2]`,
+		`{,}`, `"a":1`, `*`, `{"a\x":1}`, `"abc`, `["a`,
+	}
+	for _, v := range s {
+		h := &recordingHandler{}
+		d := NewEventDecoder(h, EventDecoderOptUnescape)
+		r := bytes.NewReader([]byte(v))
+
+		e := &SyntaxError{}
+		if err := d.Decode(r); !errors.As(err, &e) {
+			t.Errorf("unexpected %q %v", v, err)
+		}
+	}
+}
+
+// expect handler error to stop decoding
+func TestEventDecoder_4(t *testing.T) {
+	h := &recordingHandler{failAt: 2}
+	d := NewEventDecoder(h)
+	r := bytes.NewReader([]byte(`[1, 2, 3]`))
+
+	if err := d.Decode(r); err == nil || err.Error() != "fail" {
+		t.Errorf("unexpected %v", err)
+	}
+	if len(h.events) != 2 {
+		t.Error("unexpected")
+	}
+}
+
+// expect reader error
+func TestEventDecoder_5(t *testing.T) {
+	d := NewEventDecoder(&recordingHandler{})
+
+	if err := d.Decode(&FaultyReader{}); err == nil {
+		t.Error("unexpected")
+	}
+}
@@ -0,0 +1,117 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+type (
+	// Grammar validates a sequence of token kinds against the
+	// structural rules of RFC 8259, e.g. in a jsonlex.Yield function.
+	// A stream of concatenated top level values is accepted. The zero
+	// value is ready to use.
+	Grammar struct {
+		nest  []TokenKind // open containers
+		state grammarState
+	}
+
+	grammarState uint8
+)
+
+const (
+	expectValue        grammarState = iota // top level, after COL or COM in array
+	expectValueOrClose                     // after LSB
+	expectKeyOrClose                       // after LCB
+	expectKey                              // after COM in object
+	expectColon                            // after member name
+	expectCommaOrClose                     // after value in container
+)
+
+// Push validates the next token kind. The first return
+// value reports whether a jsonlex.TokenSTR is an object
+// member name, the second whether the kind was expected.
+// Punctuation must be pushed, jsonlex.TokenEOF and
+// jsonlex.TokenERR must not.
+func (g *Grammar) Push(kind TokenKind) (bool, bool) {
+	kind = kind.plain()
+
+	switch g.state {
+	case expectKeyOrClose, expectKey:
+		if kind.Is(TokenSTR) {
+			g.state = expectColon
+			return true, true
+		}
+		if kind.Is(TokenRCB) && g.state == expectKeyOrClose {
+			return false, g.close(kind)
+		}
+		return false, false
+
+	case expectColon:
+		if kind.Is(TokenCOL) {
+			g.state = expectValue
+			return false, true
+		}
+		return false, false
+
+	case expectCommaOrClose:
+		switch kind {
+		case TokenCOM:
+			if g.nest[len(g.nest)-1].Is(TokenLCB) {
+				g.state = expectKey
+			} else {
+				g.state = expectValue
+			}
+			return false, true
+		case TokenRCB, TokenRSB:
+			return false, g.close(kind)
+		}
+		return false, false
+	}
+
+	// expectValue, expectValueOrClose
+	switch kind {
+	case TokenSTR, TokenNUM, TokenLIT:
+		g.value()
+		return false, true
+	case TokenLCB:
+		g.nest = append(g.nest, kind)
+		g.state = expectKeyOrClose
+		return false, true
+	case TokenLSB:
+		g.nest = append(g.nest, kind)
+		g.state = expectValueOrClose
+		return false, true
+	case TokenRSB:
+		return false, g.state == expectValueOrClose && g.close(kind)
+	}
+	return false, false
+}
+
+// Done reports whether the token stream may end here.
+func (g *Grammar) Done() bool {
+	return len(g.nest) == 0 && g.state == expectValue
+}
+
+func (g *Grammar) close(kind TokenKind) bool {
+	n := len(g.nest)
+	if n == 0 {
+		return false
+	}
+	if open := g.nest[n-1]; open.Is(TokenLCB) != kind.Is(TokenRCB) {
+		return false
+	}
+	g.nest = g.nest[:n-1]
+	g.value()
+	return true
+}
+
+func (g *Grammar) value() {
+	if len(g.nest) == 0 {
+		g.state = expectValue
+	} else {
+		g.state = expectCommaOrClose
+	}
+}
+
+// Reset prepares the Grammar for a new token stream.
+func (g *Grammar) Reset() {
+	g.nest = g.nest[:0]
+	g.state = expectValue
+}
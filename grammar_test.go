@@ -0,0 +1,49 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"testing"
+)
+
+func grammarOf(s string) (keys int, ok bool, done bool) {
+	var g Grammar
+	ok = true
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenEOF) || kind.Is(TokenERR) {
+			return false
+		}
+		key, valid := g.Push(kind)
+		if key {
+			keys++
+		}
+		ok = ok && valid
+		return ok
+	}, LexerOptEmitKeys, LexerOptEmitNumberKinds)
+	l.Scan(bytes.NewReader([]byte(s)))
+	return keys, ok, g.Done()
+}
+
+func TestGrammar_Push_1(t *testing.T) {
+	for s, e := range map[string]int{
+		`1`: 0, `{}`: 0, `[]`: 0, `{"a": {"b": [1.5, "c"]}, "d": null}`: 3, `[{"a": 1}] "x" 2`: 1,
+	} {
+		if keys, ok, done := grammarOf(s); keys != e || !ok || !done {
+			t.Errorf("unexpected %d %v %v for %s", keys, ok, done, s)
+		}
+	}
+}
+
+func TestGrammar_Push_2(t *testing.T) {
+	for _, s := range []string{`{"a"}`, `{1: 2}`, `[1 2]`, `[1,]`, `{"a": 1,}`, `]`, `[}`, `,`, `:`, `{"a": 1 "b": 2}`} {
+		if _, ok, _ := grammarOf(s); ok {
+			t.Errorf("unexpected valid %s", s)
+		}
+	}
+	for _, s := range []string{`[`, `{"a"`, `{"a":`, `[1,`} {
+		if _, ok, done := grammarOf(s); !ok || done {
+			t.Errorf("unexpected %v %v for %s", ok, done, s)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// ScanIndexed tokenizes a complete in-memory buffer in two stages.
+// The first stage computes an index of structural characters, string
+// boundaries and scalar starts, processing eight bytes at a time (SWAR).
+// The second stage emits the tokens from the index, the loads reference
+// the buffer. The emitted tokens are identical to those of Scan(), but
+// ScanIndexed is not reentrant.
+func (l *Lexer) ScanIndexed(data []byte) {
+	l.reset()
+	l.index = buildIndex(data, l.index[:0])
+	l.emitIndexed(data, l.index)
+}
+
+// emitIndexed is the second stage of ScanIndexed().
+func (l *Lexer) emitIndexed(data []byte, idx []uint32) {
+	var (
+		n = len(data)
+		i = 0 // position in index
+		p = 0 // next possible token start in data
+	)
+
+	for {
+		if p >= n || isSpace(data[p]) {
+			for i < len(idx) && int(idx[i]) < p {
+				i++
+			}
+			if i == len(idx) {
+				l.tpos, l.bpos = uint(n), uint(n)
+				l.yield(TokenEOF, nil, uint(n))
+				return
+			}
+			p = int(idx[i])
+		}
+
+		b := data[p]
+		if b > 0x7F {
+			l.emitUnexpected(b, p)
+			return
+		}
+		start, kind := p, states[b]
+		l.tpos = uint(start)
+
+		switch kind {
+		case TokenSTR:
+			for i < len(idx) && int(idx[i]) <= start {
+				i++
+			}
+			if i == len(idx) || data[idx[i]] != '"' {
+				l.bpos = uint(n)
+				l.err = &SyntaxError{Msg: "unterminated string", Pos: uint(start)}
+				l.yield(TokenERR, []byte("unterminated string"), uint(start))
+				return
+			}
+			p = int(idx[i]) + 1
+			l.bpos = uint(p)
+			if !l.emit(TokenSTR, data[start+1:p-1], uint(start)) {
+				return
+			}
+
+		case TokenNUM:
+			if p = scanNumber(data, start); !validNumber(data[start:p]) {
+				l.emitUnexpected(terminator(data, p), start)
+				return
+			}
+			l.bpos = uint(p)
+			if !l.emit(TokenNUM, data[start:p], uint(start)) {
+				return
+			}
+
+		case TokenLIT:
+			for p = start + 1; p < n && data[p] >= 'a' && data[p] <= 'z'; p++ {
+			}
+			if s := string(data[start:p]); s != "null" && s != "true" && s != "false" {
+				l.emitUnexpected(terminator(data, p), start)
+				return
+			}
+			l.bpos = uint(p)
+			if !l.emit(TokenLIT, data[start:p], uint(start)) {
+				return
+			}
+
+		case 0:
+			l.emitUnexpected(b, start)
+			return
+
+		default:
+			p = start + 1
+			l.bpos = uint(p)
+			if !l.emit(kind, data[start:p], uint(start)) {
+				return
+			}
+		}
+	}
+}
+
+func (l *Lexer) emitUnexpected(b byte, pos int) {
+	m := fmt.Sprintf("unexpected %q (0x%X)", b, b)
+	l.tpos = uint(pos)
+	l.err = &SyntaxError{Msg: m, Pos: uint(pos)}
+	l.yield(TokenERR, []byte(m), uint(pos))
+}
+
+// scanNumber mirrors the number rules of Scan() and returns the end.
+func scanNumber(data []byte, i int) int {
+	frac, expo, sign := false, false, false
+
+	for i++; i < len(data); i++ {
+		switch b := data[i]; {
+		case b >= '0' && b <= '9':
+			sign = false
+		case !frac && b == '.':
+			frac = true
+		case !expo && (b == 'e' || b == 'E'):
+			frac, expo, sign = true, true, true
+		case sign && (b == '+' || b == '-'):
+			sign = false
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func validNumber(num []byte) bool {
+	if b := num[len(num)-1]; b == '.' || b == '-' || b == 'e' || b == 'E' {
+		return false
+	}
+	if len(num) >= 3 {
+		if s := string(num[:3]); s == "-.e" || s == "-.E" {
+			return false
+		}
+	}
+	return true
+}
+
+// terminator returns the byte which ended a token, which is
+// the last byte of the token at the end of the buffer.
+func terminator(data []byte, end int) byte {
+	if end < len(data) {
+		return data[end]
+	}
+	return data[end-1]
+}
+
+func isSpace(b byte) bool {
+	return b == 0x20 || b == '\n' || b == '\r' || b == '\t'
+}
+
+// buildIndex is the first stage of ScanIndexed(). It appends the positions
+// of structural characters, unescaped quotes and scalar starts outside of
+// strings to idx. Each block of 64 bytes is turned into bit masks.
+func buildIndex(data []byte, idx []uint32) []uint32 {
+	var (
+		block   [64]byte
+		esc     bool   // next block starts escaped
+		str     uint64 // all ones when next block starts inside string
+		sep     uint64 = 1
+		quo, bs uint64
+		ops, ws uint64
+	)
+
+	for base := 0; base < len(data); base += 64 {
+		chunk := data[base:]
+		if len(chunk) < 64 {
+			n := copy(block[:], chunk)
+			for j := n; j < 64; j++ {
+				block[j] = ' '
+			}
+			chunk = block[:]
+		}
+		quo, bs, ops, ws = classify(chunk)
+
+		// escaped characters, backslashes are rare
+		escaped := uint64(0)
+		if esc {
+			escaped = 1
+		}
+		esc = false
+		for m := bs &^ escaped; m != 0; m &= m - 1 {
+			j := uint(bits.TrailingZeros64(m))
+			if escaped&(1<<j) != 0 {
+				continue
+			}
+			if j == 63 {
+				esc = true
+			} else {
+				escaped |= 1 << (j + 1)
+			}
+		}
+
+		quo &^= escaped
+		inside := prefixXor(quo) ^ str
+		str = uint64(int64(inside) >> 63)
+
+		seps := ws | ops | quo
+		starts := ^seps & (seps<<1 | sep)
+		sep = seps >> 63
+
+		for m := (ops|starts)&^inside | quo; m != 0; m &= m - 1 {
+			if pos := base + bits.TrailingZeros64(m); pos < len(data) {
+				idx = append(idx, uint32(pos))
+			}
+		}
+	}
+	return idx
+}
+
+// classify returns bit masks for the first 64 bytes of chunk: quotes,
+// backslashes, structural characters and whitespace.
+func classify(chunk []byte) (quo, bs, ops, ws uint64) {
+	for k := uint(0); k < 8; k++ {
+		c := chunk[8*k : 8*k+8]
+		x := uint64(c[0]) | uint64(c[1])<<8 | uint64(c[2])<<16 | uint64(c[3])<<24 |
+			uint64(c[4])<<32 | uint64(c[5])<<40 | uint64(c[6])<<48 | uint64(c[7])<<56
+
+		quo |= movemask(eq(x, '"')) << (8 * k)
+		bs |= movemask(eq(x, '\\')) << (8 * k)
+		ops |= movemask(eq(x|lsb*0x20, '{')|eq(x|lsb*0x20, '}')|eq(x, ',')|eq(x, ':')) << (8 * k)
+		ws |= movemask(eq(x, ' ')|eq(x, '\n')|eq(x, '\r')|eq(x, '\t')) << (8 * k)
+	}
+	return quo, bs, ops, ws
+}
+
+// eq sets the high bit of each byte of x which equals b.
+func eq(x uint64, b byte) uint64 {
+	x ^= lsb * uint64(b)
+	return ^((x&^msb + ^msb) | x | ^msb)
+}
+
+// movemask gathers the high bits of the eight bytes into one byte.
+func movemask(m uint64) uint64 {
+	return (m >> 7) * 0x0102040810204080 >> 56
+}
+
+// prefixXor sets each bit to the parity of all lower and equal bits.
+func prefixXor(x uint64) uint64 {
+	x ^= x << 1
+	x ^= x << 2
+	x ^= x << 4
+	x ^= x << 8
+	x ^= x << 16
+	x ^= x << 32
+	return x
+}
+
+const (
+	lsb uint64 = 0x0101010101010101
+	msb uint64 = 0x8080808080808080
+)
@@ -0,0 +1,100 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func indexedTokens(data []byte, stopAt int) []string {
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, fmt.Sprintf("%d:%d:%s", pos, kind, load))
+		return len(toks) != stopAt
+	}
+	l := NewLexer(y)
+	if stopAt < 0 {
+		l.Scan(bytes.NewReader(data))
+	} else {
+		l.ScanIndexed(data)
+	}
+	return toks
+}
+
+// expect the same tokens as Scan()
+func TestLexer_ScanIndexed_1(t *testing.T) {
+	s := []string{
+		``, ` `, `*`, "\x05", "\x7F", "\x80", `{}`, `[1,2]`, `"`, `"a`, `"a"`, `"a\"`, `"\\"`,
+		` { "foo": "bar", "b\"az": [ null, true, false, -42, "false" ] } `,
+		`-0 -1 0.1e-20 1.e+5 1.0 1e+1 -.0E+0 1E-0 : true false null`,
+		`- -- +1 . -0. -E -e .E .e -.E -.e 1e -.e0 .e0 1E-+0 1e.`,
+		`frue`, `nalse `, `tull`, `nul`, `true12`, `12"a,b"x`, `"a""b"`, `12x`, `1.2.3`,
+		`[1,` + "\x05" + ` 2]`, `\"`, `1\`, `[` + strings.Repeat(`"\\\\\\"",`, 40) + `]`,
+		strings.Repeat(" ", 63) + `"` + strings.Repeat(`\\`, 31) + `\"x"` + strings.Repeat(" ", 70) + `1`,
+		strings.Repeat("a", 64) + `1`, strings.Repeat(" ", 64) + `nul`,
+	}
+	for _, v := range s {
+		e := indexedTokens([]byte(v), -1)
+		if toks := indexedTokens([]byte(v), 0); fmt.Sprint(toks) != fmt.Sprint(e) {
+			t.Errorf("unexpected %q\n%v\n%v", v, toks, e)
+		}
+	}
+}
+
+func TestLexer_ScanIndexed_2(t *testing.T) {
+	doc, err := ioutil.ReadFile("testdata/200kB.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := indexedTokens(doc, -1)
+	if toks := indexedTokens(doc, 0); fmt.Sprint(toks) != fmt.Sprint(e) {
+		t.Errorf("unexpected")
+	}
+}
+
+// random documents of JSON fragments
+func TestLexer_ScanIndexed_3(t *testing.T) {
+	frags := []string{
+		`{`, `}`, `[`, `]`, `,`, `:`, ` `, "\n", `"`, `\`, `\\`, `\"`, `"a\"b"`, `"x"`,
+		`1`, `-2.5e3`, `e`, `.`, `true`, `nul`, `x`, "\x01", "ä",
+	}
+	rnd := rand.New(rand.NewSource(42))
+
+	for i := 0; i < 2000; i++ {
+		var b strings.Builder
+		for j := rnd.Intn(100); j > 0; j-- {
+			b.WriteString(frags[rnd.Intn(len(frags))])
+		}
+		v := []byte(b.String())
+		e := indexedTokens(v, -1)
+		if toks := indexedTokens(v, 0); fmt.Sprint(toks) != fmt.Sprint(e) {
+			t.Fatalf("unexpected %q\n%v\n%v", v, toks, e)
+		}
+	}
+}
+
+// expect yield to stop the process
+func TestLexer_ScanIndexed_4(t *testing.T) {
+	if toks := indexedTokens([]byte(`[1, "a", true]`), 3); len(toks) != 3 {
+		t.Errorf("unexpected %v", toks)
+	}
+}
+
+func TestMovemask(t *testing.T) {
+	for i := uint64(0); i < 256; i++ {
+		m := uint64(0)
+		for k := uint(0); k < 8; k++ {
+			if i&(1<<k) != 0 {
+				m |= 0x80 << (8 * k)
+			}
+		}
+		if movemask(m) != i {
+			t.Errorf("unexpected %d", i)
+		}
+	}
+}
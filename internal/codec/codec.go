@@ -0,0 +1,57 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package codec holds the helpers shared by the decoders of the
+// binary formats.
+package codec
+
+import (
+	"io"
+	"math"
+	"strconv"
+)
+
+// AppendFloat formats like encoding/json, without an exponent for
+// magnitudes between 1e-6 and 1e21. Half and single precision values
+// are widened to float64 by the caller: the digits are those of the
+// float64, so that the text parses back to the same value.
+func AppendFloat(b []byte, f float64) []byte {
+	abs, format := math.Abs(f), byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+
+	if format == 'e' {
+		// clean up e-09 to e-9
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// ReadN appends n bytes read from r to b, growing it in chunks so
+// that a bogus length does not exhaust memory. It returns the grown
+// slice and the number of bytes read.
+func ReadN(r io.Reader, b []byte, n uint64) ([]byte, int, error) {
+	read := 0
+	for n > 0 {
+		k := uint64(4096)
+		if n < k {
+			k = n
+		}
+		l := len(b)
+		if uint64(cap(b)-l) < k {
+			b = append(b, make([]byte, k)...)
+		}
+		b = b[:l+int(k)]
+
+		m, err := io.ReadFull(r, b[l:])
+		if read += m; err != nil {
+			return b[:l+m], read, err
+		}
+		n -= k
+	}
+	return b, read, nil
+}
@@ -0,0 +1,41 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package codec
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAppendFloat_1(t *testing.T) {
+	for f, e := range map[float64]string{
+		0: "0", 1.5: "1.5", -4.1: "-4.1", 1e20: "100000000000000000000", 1e21: "1e+21",
+		1e-6: "0.000001", 1e-7: "1e-7", float64(float32(0.1)): "0.10000000149011612",
+		math.Ldexp(1, -24): "5.960464477539063e-8", -math.Ldexp(1, 64): "-18446744073709552000",
+	} {
+		b := AppendFloat(nil, f)
+		if string(b) != e {
+			t.Errorf("unexpected %s", b)
+		}
+		if p, err := strconv.ParseFloat(string(b), 64); err != nil || p != f {
+			t.Errorf("unexpected %v %v", p, err)
+		}
+	}
+}
+
+func TestReadN_1(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+
+	b, n, err := ReadN(strings.NewReader(data), []byte("x"), uint64(len(data)))
+	if err != nil || n != len(data) || string(b) != "x"+data {
+		t.Errorf("unexpected %d %v", n, err)
+	}
+	b, n, err = ReadN(bytes.NewReader([]byte(data)), nil, 1<<40)
+	if err != io.ErrUnexpectedEOF || n != len(data) || len(b) != len(data) {
+		t.Errorf("unexpected %d %v", n, err)
+	}
+}
@@ -0,0 +1,86 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package number compares and converts JSON number lexemes without rounding.
+package number
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Normal returns a representation of a number lexeme, which is
+// equal for numerically equal lexemes without rounding: the significant
+// digits, followed by zeros or the exponent, e.g. 15e-1 for 1.50.
+func Normal(load []byte) string {
+	neg, digits, exp, ok := parse(load)
+	if !ok {
+		f, _ := strconv.ParseFloat(string(load), 64) // exponent out of range
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if digits == "" {
+		return "0"
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	if exp >= 0 && exp <= 20 {
+		return digits + strings.Repeat("0", exp)
+	}
+	return digits + "e" + strconv.Itoa(exp)
+}
+
+// Integral returns the decimal integer denoted by a number lexeme
+// and whether the lexeme denotes one, e.g. 100 for 1e2 or 100.0.
+// Lexemes with fraction or exponent qualify in the range of int64 or
+// uint64, and not as negative zero. Integer lexemes are returned as
+// they are.
+func Integral(load []byte) (string, bool) {
+	if bytes.IndexAny(load, ".eE") < 0 {
+		return string(load), true
+	}
+	neg, digits, exp, ok := parse(load)
+	switch {
+	case !ok || exp < 0 || len(digits)+exp > 20:
+		return "", false
+	case digits == "" && neg:
+		return "", false
+	case digits == "":
+		return "0", true
+	case neg:
+		digits = "-" + digits
+	}
+	i := digits + strings.Repeat("0", exp)
+	if _, err := strconv.ParseInt(i, 10, 64); err == nil {
+		return i, true
+	}
+	if _, err := strconv.ParseUint(i, 10, 64); err == nil {
+		return i, true
+	}
+	return "", false
+}
+
+// parse splits a number lexeme into its sign, its significant digits
+// without leading and trailing zeros and the decimal exponent. It
+// fails when the exponent exceeds the range of int.
+func parse(load []byte) (neg bool, digits string, exp int, ok bool) {
+	s := string(load)
+	if strings.HasPrefix(s, "-") {
+		s, neg = s[1:], true
+	}
+	digits = s
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return neg, "", 0, false
+		}
+		digits, exp = s[:i], e
+	}
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		exp -= len(digits) - i - 1
+		digits = digits[:i] + digits[i+1:]
+	}
+	t := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(t)
+	return neg, strings.TrimLeft(t, "0"), exp, true
+}
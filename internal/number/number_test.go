@@ -0,0 +1,52 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package number
+
+import "testing"
+
+func TestNormal_1(t *testing.T) {
+	for s, e := range map[string]string{
+		`1.0`:                    `1`,
+		`100`:                    `100`,
+		`1e2`:                    `100`,
+		`-2.5e1`:                 `-25`,
+		`1.50`:                   `15e-1`,
+		`0.15E1`:                 `15e-1`,
+		`-0.0e5`:                 `0`,
+		`12E+30`:                 `12e30`,
+		`1e21`:                   `1e21`,
+		`9007199254740993`:       `9007199254740993`,
+		`1e99999999999999999999`: `+Inf`,
+	} {
+		if n := Normal([]byte(s)); n != e {
+			t.Errorf("unexpected %s for %s", n, s)
+		}
+	}
+}
+
+func TestIntegral_1(t *testing.T) {
+	for s, e := range map[string]string{
+		`100`:                      `100`,
+		`-0`:                       `-0`,
+		`123456789012345678901234`: `123456789012345678901234`,
+		`1e2`:                      `100`,
+		`100.0`:                    `100`,
+		`-2.5e1`:                   `-25`,
+		`0.0`:                      `0`,
+		`1.8446744073709551615e19`: `18446744073709551615`,
+		`-9.223372036854775808E18`: `-9223372036854775808`,
+		`1.8446744073709551616e19`: ``,
+		`-9.223372036854775809E18`: ``,
+		`0e99999999999999999999`:   ``,
+		`1e-99999999999999999999`:  ``,
+		`1e19`:                     `10000000000000000000`,
+		`1e20`:                     ``,
+		`1.5`:                      ``,
+		`15e-1`:                    ``,
+		`-0.0`:                     ``,
+	} {
+		if i, ok := Integral([]byte(s)); i != e || ok != (e != "") {
+			t.Errorf("unexpected %s %v for %s", i, ok, s)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package pointer escapes and splits JSON Pointers (RFC 6901).
+package pointer
+
+import "strings"
+
+var (
+	escaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// Escape returns the member name as a segment of a JSON Pointer.
+func Escape(s string) string {
+	if strings.IndexAny(s, "~/") < 0 {
+		return s
+	}
+	return escaper.Replace(s)
+}
+
+// Split returns the unescaped segments of a JSON Pointer,
+// none for the empty pointer, which denotes the whole document.
+func Split(p string) []string {
+	if p == "" {
+		return nil
+	}
+	segs := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	for i, s := range segs {
+		segs[i] = unescaper.Replace(s)
+	}
+	return segs
+}
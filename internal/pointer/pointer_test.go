@@ -0,0 +1,33 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package pointer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscape_1(t *testing.T) {
+	for s, e := range map[string]string{"a": "a", "a/b~c": "a~1b~0c", "~1": "~01", "": ""} {
+		if p := Escape(s); p != e {
+			t.Errorf("unexpected %s", p)
+		}
+		if segs := Split("/" + Escape(s)); len(segs) != 1 || segs[0] != s {
+			t.Errorf("unexpected %q", segs)
+		}
+	}
+}
+
+func TestSplit_1(t *testing.T) {
+	for p, e := range map[string][]string{
+		"":           nil,
+		"/":          {""},
+		"/a/0":       {"a", "0"},
+		"/a~1b/~0/*": {"a/b", "~", "*"},
+		"/~01":       {"~1"},
+	} {
+		if segs := Split(p); !reflect.DeepEqual(segs, e) {
+			t.Errorf("unexpected %q", segs)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+//go:build go1.23
+
+package jsonlex
+
+import (
+	"io"
+	"iter"
+)
+
+// Tokens returns an iterator over the tokens of the byte stream.
+// The sequence ends before jsonlex.TokenEOF, a jsonlex.TokenERR
+// is the last element of a failing sequence.
+//
+// Like Scan(), the iterator is reentrant: ranging over it again after
+// an early break continues with the next token. Please note, that the
+// Load of a Token is only valid until the next iteration step.
+func Tokens(r io.Reader, opts ...lexerOpt) iter.Seq[Token] {
+	var yield func(Token) bool
+
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenEOF) {
+			return false
+		}
+		return yield(Token{kind, load, pos})
+	}, opts...)
+
+	return func(y func(Token) bool) {
+		yield = y
+		l.Scan(r)
+	}
+}
+
+// TokensErr returns an iterator over the tokens of the byte stream
+// paired with an error. The error is nil for all tokens but the
+// last jsonlex.TokenERR, which is paired with the cause of failure
+// (see Lexer.Err). The sequence ends before jsonlex.TokenEOF.
+//
+// Like Scan(), the iterator is reentrant: ranging over it again after
+// an early break continues with the next token. Please note, that the
+// Load of a Token is only valid until the next iteration step.
+func TokensErr(r io.Reader, opts ...lexerOpt) iter.Seq2[Token, error] {
+	var (
+		yield func(Token, error) bool
+		l     *Lexer
+	)
+
+	l = NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenEOF) {
+			return false
+		}
+		if kind.Is(TokenERR) {
+			return yield(Token{kind, load, pos}, l.Err())
+		}
+		return yield(Token{kind, load, pos}, nil)
+	}, opts...)
+
+	return func(y func(Token, error) bool) {
+		yield = y
+		l.Scan(r)
+	}
+}
@@ -0,0 +1,105 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+//go:build go1.23
+
+package jsonlex
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestTokens_1(t *testing.T) {
+	s := `{ "foo": [ null, -1 ] }`
+	r := bytes.NewReader([]byte(s))
+
+	e := []TokenKind{
+		TokenLCB, TokenSTR, TokenCOL, TokenLSB,
+		TokenLIT, TokenCOM, TokenNUM, TokenRSB, TokenRCB,
+	}
+
+	i := 0
+	for tok := range Tokens(r) {
+		if i >= len(e) || !tok.Is(e[i]) {
+			t.Errorf("unexpected %q", tok.Load)
+		}
+		i++
+	}
+	if i != len(e) {
+		t.Error("unexpected")
+	}
+}
+
+// early break and re-entrance
+func TestTokens_2(t *testing.T) {
+	s := `[1,2] [3]`
+	r := bytes.NewBuffer([]byte(s))
+	seq := Tokens(r, LexerOptEnableUnreadBuffer)
+
+	for tok := range seq {
+		if tok.Is(TokenRSB) {
+			break
+		}
+	}
+	if r.String() != ` [3]` {
+		t.Errorf("unexpected %q", r.String())
+	}
+
+	i := 0
+	for tok := range seq {
+		if i == 1 && tok.String() != "3" {
+			t.Errorf("unexpected %q", tok.Load)
+		}
+		i++
+	}
+	if i != 3 {
+		t.Error("unexpected")
+	}
+}
+
+func TestTokens_3(t *testing.T) {
+	r := &FaultyReader{}
+
+	i := 0
+	for tok := range Tokens(r) {
+		if !tok.Is(TokenERR) {
+			t.Errorf("unexpected %q", tok.Load)
+		}
+		i++
+	}
+	if i != 1 {
+		t.Error("unexpected")
+	}
+}
+
+func TestTokensErr_1(t *testing.T) {
+	s := `[true, * ]`
+	r := bytes.NewReader([]byte(s))
+
+	i := 0
+	for tok, err := range TokensErr(r) {
+		if i < 3 && err != nil {
+			t.Errorf("unexpected %v", err)
+		}
+		if i == 3 {
+			e := &SyntaxError{}
+			if !errors.As(err, &e) || e.Pos != 7 || !tok.Is(TokenERR) {
+				t.Errorf("unexpected %v", err)
+			}
+		}
+		i++
+	}
+	if i != 4 {
+		t.Error("unexpected")
+	}
+}
+
+func TestTokensErr_2(t *testing.T) {
+	for _, err := range TokensErr(&FaultyReader{}) {
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("unexpected %v", err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package jcs re-emits JSON documents in the canonical form of RFC 8785
+// (JSON Canonicalization Scheme): without whitespace, object members
+// sorted by the UTF-16 code units of their names, strings with minimal
+// escaping and numbers serialized like ECMAScript does.
+package jcs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/dtgorski/jsonlex/dom"
+)
+
+// Canonicalize returns the canonical form of a buffer which must contain
+// exactly one JSON value. Duplicate member names, invalid escape sequences
+// and escaped lone UTF-16 surrogates result in an error.
+func Canonicalize(data []byte) ([]byte, error) {
+	v, err := dom.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSurrogates(data); err != nil {
+		return nil, err
+	}
+	return appendValue(make([]byte, 0, len(data)), v)
+}
+
+func appendValue(b []byte, v dom.Value) ([]byte, error) {
+	switch v.Kind() {
+	case dom.Object:
+		return appendObject(b, v)
+	case dom.Array:
+		return appendArray(b, v)
+	case dom.String:
+		return appendString(b, v.String()), nil
+	case dom.Number:
+		f, err := v.Float()
+		if err != nil {
+			return nil, err
+		}
+		return appendNumber(b, f), nil
+	}
+	return append(b, v.Raw()...), nil
+}
+
+type member struct {
+	name  string
+	units []uint16
+	value dom.Value
+}
+
+func appendObject(b []byte, v dom.Value) ([]byte, error) {
+	members := make([]member, 0, v.Len())
+	seen := make(map[string]bool, v.Len())
+
+	var err error
+	v.ForEach(func(_ int, m dom.Value) bool {
+		k := m.Key()
+		if seen[k] {
+			err = fmt.Errorf("%w %q", errDuplicateKey, k)
+			return false
+		}
+		seen[k] = true
+		members = append(members, member{k, utf16.Encode([]rune(k)), m})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return less(members[i].units, members[j].units)
+	})
+
+	b = append(b, '{')
+	for i, m := range members {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendString(b, m.name)
+		b = append(b, ':')
+		if b, err = appendValue(b, m.value); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+func appendArray(b []byte, v dom.Value) ([]byte, error) {
+	var err error
+	b = append(b, '[')
+	v.ForEach(func(i int, e dom.Value) bool {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b, err = appendValue(b, e)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(b, ']'), nil
+}
+
+// appendString escapes only quotation mark, reverse solidus
+// and control characters, as required by RFC 8785.
+func appendString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\t':
+			b = append(b, '\\', 't')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\r':
+			b = append(b, '\\', 'r')
+		default:
+			if c < 0x20 {
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+// appendNumber serializes like Number.prototype.toString() of ECMAScript.
+func appendNumber(b []byte, f float64) []byte {
+	if f == 0 {
+		return append(b, '0') // including -0
+	}
+	if f < 0 {
+		b, f = append(b, '-'), -f
+	}
+
+	// shortest round-trip digits d.ddde±x
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(e, 'e')
+	digits := strings.Replace(e[:i], ".", "", 1)
+	exp, _ := strconv.Atoi(e[i+1:])
+
+	k, n := len(digits), exp+1 // number of digits, position of decimal point
+
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		b = append(b, strings.Repeat("0", n-k)...)
+	case 0 < n && n <= 21:
+		b = append(b, digits[:n]...)
+		b = append(b, '.')
+		b = append(b, digits[n:]...)
+	case -6 < n && n <= 0:
+		b = append(b, "0."...)
+		b = append(b, strings.Repeat("0", -n)...)
+		b = append(b, digits...)
+	default:
+		b = append(b, digits[0])
+		if k > 1 {
+			b = append(b, '.')
+			b = append(b, digits[1:]...)
+		}
+		b = append(b, 'e')
+		if n-1 >= 0 {
+			b = append(b, '+')
+		}
+		b = strconv.AppendInt(b, int64(n-1), 10)
+	}
+	return b
+}
+
+// checkSurrogates reports escaped surrogates which do not form a pair,
+// since they have no representation in UTF-8. The data must have been
+// parsed, so every reverse solidus starts an escape sequence in a string.
+func checkSurrogates(data []byte) error {
+	high := -1 // position of a pending high surrogate
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\\' {
+			continue
+		}
+		if i++; data[i] != 'u' {
+			if high >= 0 {
+				return fmt.Errorf("%w at %d", errLoneSurrogate, high)
+			}
+			continue
+		}
+		r, _ := strconv.ParseUint(string(data[i+1:i+5]), 16, 16)
+		switch {
+		case 0xD800 <= r && r < 0xDC00:
+			if high >= 0 {
+				return fmt.Errorf("%w at %d", errLoneSurrogate, high)
+			}
+			high = i - 1
+		case 0xDC00 <= r && r < 0xE000:
+			if high < 0 || high != i-7 {
+				return fmt.Errorf("%w at %d", errLoneSurrogate, i-1)
+			}
+			high = -1
+		default:
+			if high >= 0 {
+				return fmt.Errorf("%w at %d", errLoneSurrogate, high)
+			}
+		}
+		i += 4
+	}
+	if high >= 0 {
+		return fmt.Errorf("%w at %d", errLoneSurrogate, high)
+	}
+	return nil
+}
+
+func less(a, b []uint16) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+var (
+	errDuplicateKey  = errors.New("duplicate member name")
+	errLoneSurrogate = errors.New("lone surrogate")
+)
@@ -0,0 +1,79 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jcs
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCanonicalize_1(t *testing.T) {
+	s := `{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`
+	e := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],` +
+		`"string":"€$\u000f\nA'B\"\\\\\"/"}`
+
+	b, err := Canonicalize([]byte(s))
+	if err != nil || string(b) != e {
+		t.Errorf("unexpected %s %v", b, err)
+	}
+}
+
+// sorting example of RFC 8785, section 3.2.3
+func TestCanonicalize_2(t *testing.T) {
+	s := `{"€": "Euro Sign", "\r": "Carriage Return", "דּ": "Hebrew Letter Dalet With Dagesh",
+		"1": "One", "😀": "Emoji: Grinning Face", "\u0080": "Control", "ö": "Latin Small Letter O With Diaeresis"}`
+	e := `{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis",` +
+		`"€":"Euro Sign","` + "\U0001F600" + `":"Emoji: Grinning Face","` + "דּ" + `":"Hebrew Letter Dalet With Dagesh"}`
+
+	b, err := Canonicalize([]byte(s))
+	if err != nil || string(b) != e {
+		t.Errorf("unexpected %s %v", b, err)
+	}
+}
+
+func TestCanonicalize_3(t *testing.T) {
+	if _, err := Canonicalize([]byte(`{"a": 1, "b": {"c": 1, "c": 2}}`)); !errors.Is(err, errDuplicateKey) {
+		t.Errorf("unexpected %v", err)
+	}
+	for _, s := range []string{``, `[1,]`, `1e400`, `1 2`} {
+		if _, err := Canonicalize([]byte(s)); err == nil {
+			t.Errorf("unexpected nil error %s", s)
+		}
+	}
+}
+
+func TestCanonicalize_4(t *testing.T) {
+	b, err := Canonicalize([]byte(`{"\ud83d\ude00": "\\uD800\uFFFD"}`))
+	if err != nil || string(b) != `{"`+"\U0001F600"+`":"\\uD800`+"\uFFFD"+`"}` {
+		t.Errorf("unexpected %s %v", b, err)
+	}
+	for _, s := range []string{
+		`"\uD800"`, `"\uDC00"`, `"\uD800\n"`, `"\uD800\uD800\uDC00"`, `"\uD800x\uDC00"`,
+		`["\uD800", "\uDC00"]`, `{"\uDFFF": 1}`, `"\uD83D\u0041"`, `"\x"`, `"\u12"`,
+	} {
+		if _, err := Canonicalize([]byte(s)); err == nil {
+			t.Errorf("unexpected nil error %s", s)
+		}
+	}
+}
+
+func TestAppendNumber_1(t *testing.T) {
+	for f, e := range map[float64]string{
+		0: "0", 1: "1", -1.5: "-1.5", 1e21: "1e+21", 1e20: "100000000000000000000",
+		123e-20: "1.23e-18", 1e-6: "0.000001", 1e-7: "1e-7", 9007199254740992: "9007199254740992",
+		295147905179352830000: "295147905179352830000", 1.7976931348623157e308: "1.7976931348623157e+308",
+		5e-324: "5e-324", 0.1: "0.1", 12.34e5: "1234000", -5e-7: "-5e-7",
+	} {
+		if s := string(appendNumber(nil, f)); s != e {
+			t.Errorf("unexpected %s for %s", s, e)
+		}
+	}
+	if s := string(appendNumber(nil, math.Copysign(0, -1))); s != "0" {
+		t.Errorf("unexpected %s", s)
+	}
+}
@@ -4,8 +4,10 @@
 package jsonlex
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"hash/maphash"
 	"io"
 )
 
@@ -24,7 +26,23 @@ type (
 		esc   bool    // string escaping mode
 		burd  bool    // is true if buffer was unread
 		burde bool    // unread feature (if supported) enabled
-
+		ctx   context.Context
+		ctxn  uint      // bytes left until next context check
+		err   error     // cause of the last jsonlex.TokenERR
+		susp  bool      // partial token suspended (push mode)
+		kind  TokenKind // kind of suspended token
+		push  chunkReader
+		index []uint32    // structural index (ScanIndexed)
+		nest  []TokenKind // open containers
+		prev  TokenKind   // previously emitted token
+		keys  bool        // emit jsonlex.TokenKEY
+		nums  bool        // emit jsonlex.TokenINT and jsonlex.TokenFLT
+		dupe  bool        // reject duplicate member names
+		warn  func(*DuplicateKeyError)
+		track bool              // tokens pass through emit()
+		names []map[uint64]uint // member name hashes per depth
+		ubuf  []byte            // unescaped member name
+		hash  maphash.Hash      // member name hashing, seeded on first use
 	}
 
 	// Yield is a callback function. It will be invoked
@@ -60,14 +78,38 @@ var (
 	LexerOptEnableUnreadBuffer lexerOpt = func(l *Lexer) {
 		l.burde = true
 	}
+
+	// LexerOptEmitKeys enables the emission of jsonlex.TokenKEY
+	// for object member names, which are emitted as jsonlex.TokenSTR
+	// otherwise. The Lexer tracks the structure of the document for
+	// this purpose, but does not validate it.
+	LexerOptEmitKeys lexerOpt = func(l *Lexer) {
+		l.keys, l.track = true, true
+	}
+
+	// LexerOptEmitNumberKinds enables the emission of jsonlex.TokenINT
+	// and jsonlex.TokenFLT for integer and float lexemes, which are
+	// emitted as jsonlex.TokenNUM otherwise.
+	LexerOptEmitNumberKinds lexerOpt = func(l *Lexer) {
+		l.nums, l.track = true, true
+	}
+
+	// LexerOptTrackDepth enables Depth(), InObject() and InArray().
+	// LexerOptEmitKeys, LexerOptEmitNumberKinds and the detection of
+	// duplicate member names imply it. Without these options, the
+	// tokens are yielded without tracking the structure.
+	LexerOptTrackDepth lexerOpt = func(l *Lexer) {
+		l.track = true
+	}
 )
 
 // Scan reads and tokenizes the byte stream.
 // The yield function is invoked for each token found.
 //
 // The Scan() function terminates in following cases:
-//   a) when the yield function return false
-//   b) after emitting a jsonlex.TokenEOF or jsonlex.TokenERR
+//
+//	a) when the yield function return false
+//	b) after emitting a jsonlex.TokenEOF or jsonlex.TokenERR
 //
 // Important: The Scan() function is reentrant, subsequent invocations will
 // continue to consume the available byte stream as long as you provide
@@ -75,16 +117,24 @@ var (
 // the Lexer with the LexerOptEnableUnreadBuffer option activated.
 func (l *Lexer) Scan(r io.Reader) {
 	var (
-		b   byte      // byte under scrutiny
-		n   int       // number of bytes read
-		t   TokenKind // current token or state
-		err error     // ordinary error holder
+		b    byte      // byte under scrutiny
+		n    int       // number of bytes read
+		t    TokenKind // current token or state
+		err  error     // ordinary error holder
+		load []byte    // token payload
 	)
+	l.err = nil
+
+	if l.susp {
+		l.susp = false
+		t, load, b = l.kind, l.area, l.buff[0] // last byte read
+		goto nextByte
+	}
 
 nextToken:
 	l.esc, l.frac = false, false
 	l.expo, l.sign = false, false
-	load := l.area[:0]
+	load = l.area[:0]
 	t = scanning
 
 	if l.burd {
@@ -109,12 +159,30 @@ nextByte:
 	if l.hold {
 		l.hold = false
 	} else {
+		if l.ctx != nil {
+			if l.ctxn == 0 {
+				l.ctxn = ctxCheckInterval
+				if err = l.ctx.Err(); err != nil {
+					goto emitErrToken
+				}
+			}
+			l.ctxn--
+		}
 		n, err = r.Read(l.buff[:])
 		l.burd = false
 		l.bpos += uint(n)
 	}
 
 	if err != nil {
+		if err == errSuspend {
+			if t != scanning {
+				l.susp, l.kind, l.area = true, t, load
+			}
+			return
+		}
+		if err == io.EOF && t.Is(TokenSTR) {
+			goto emitStrErrToken
+		}
 		if err == io.EOF && len(load) > 0 && t.Is(TokenNUM) {
 			goto emitNumToken
 		}
@@ -147,11 +215,11 @@ nextByte:
 	if b == 0x20 || b == '\n' || b == '\r' || b == '\t' {
 		goto nextByte
 	}
+	l.tpos = l.bpos - 1
 	if b > 0x7F || b < 0x20 {
 		goto emitUnexpErrToken
 	}
 
-	l.tpos = l.bpos - 1
 	if s := states[b]; s != 0 {
 		t = s
 		if b == '"' {
@@ -162,11 +230,18 @@ nextByte:
 
 emitUnexpErrToken:
 	if m := fmt.Sprintf("unexpected %q (0x%X)", b, b); true {
+		l.err = &SyntaxError{Msg: m, Pos: l.tpos}
 		l.yield(TokenERR, []byte(m), l.tpos)
 	}
 	return
 
+emitStrErrToken:
+	l.err = &SyntaxError{Msg: "unterminated string", Pos: l.tpos}
+	l.yield(TokenERR, []byte("unterminated string"), l.tpos)
+	return
+
 emitErrToken:
+	l.err = err
 	l.yield(TokenERR, []byte(err.Error()), l.tpos)
 	return
 
@@ -199,7 +274,13 @@ emitTokenHold:
 	}
 
 emitToken:
-	if l.yield(t, load, l.tpos) {
+	if !l.track {
+		if l.yield(t, load, l.tpos) {
+			goto nextToken
+		}
+		return
+	}
+	if l.emit(t, load, l.tpos) {
 		goto nextToken
 	}
 	return
@@ -272,6 +353,93 @@ consume:
 	goto nextByte
 }
 
+// ScanContext works like Scan(), additionally the given context is
+// checked periodically while consuming the byte stream. When the context
+// is done, a jsonlex.TokenERR carrying the ctx.Err() message is emitted.
+//
+// Please note, that a blocking Read() of the underlying io.Reader can not
+// be interrupted, the cancellation will be noticed after Read() returns.
+func (l *Lexer) ScanContext(ctx context.Context, r io.Reader) {
+	l.ctx, l.ctxn = ctx, 0
+	l.Scan(r)
+	l.ctx = nil
+}
+
+// emit tracks the structure of the document and
+// yields the token, of a refined kind if enabled.
+func (l *Lexer) emit(kind TokenKind, load []byte, pos uint) bool {
+	if !l.track {
+		return l.yield(kind, load, pos)
+	}
+	switch kind {
+	case TokenLCB, TokenLSB:
+		l.prev = kind
+		ok := l.yield(kind, load, pos)
+		if kind == TokenLCB && (l.dupe || l.warn != nil) {
+			l.openNames()
+		}
+		l.nest = append(l.nest, kind)
+		return ok
+	case TokenRCB, TokenRSB:
+		if n := len(l.nest); n > 0 {
+			l.nest = l.nest[:n-1]
+		}
+	case TokenSTR:
+		if (l.prev == TokenLCB || l.prev == TokenCOM) && l.InObject() {
+			if (l.dupe || l.warn != nil) && !l.checkName(load, pos) {
+				return false
+			}
+			if l.keys {
+				kind = TokenKEY
+			}
+		}
+	case TokenNUM:
+		if l.nums {
+			kind = TokenINT
+			for _, b := range load {
+				if b == '.' || b == 'e' || b == 'E' {
+					kind = TokenFLT
+					break
+				}
+			}
+		}
+	}
+	l.prev = kind
+
+	return l.yield(kind, load, pos)
+}
+
+// Depth returns the number of objects and arrays enclosing the token
+// being yielded, it is valid during the invocation of the yield function.
+// Brackets belong to the enclosing level, the brackets of a top level
+// value are yielded at depth 0. It requires LexerOptTrackDepth.
+func (l *Lexer) Depth() int {
+	return len(l.nest)
+}
+
+// InObject returns whether the token being yielded is enclosed by an
+// object, it is valid during the invocation of the yield function.
+func (l *Lexer) InObject() bool {
+	return len(l.nest) > 0 && l.nest[len(l.nest)-1] == TokenLCB
+}
+
+// InArray returns whether the token being yielded is enclosed by an
+// array, it is valid during the invocation of the yield function.
+func (l *Lexer) InArray() bool {
+	return len(l.nest) > 0 && l.nest[len(l.nest)-1] == TokenLSB
+}
+
+// Err returns the error which caused the most recent jsonlex.TokenERR
+// emitted by the current Scan() invocation, nil otherwise. The error
+// is either a *SyntaxError, an error reported by the io.Reader or the
+// error of a done context.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// number of bytes consumed between two context checks
+const ctxCheckInterval = 1024
+
 var states = [0x80]TokenKind{
 	' ':  0,        // 0x20 space
 	'!':  0,        // 0x21 exclamation mark
@@ -379,4 +547,14 @@ type UnreadableReader interface {
 	UnreadByte() error
 }
 
+// SyntaxError describes a malformed byte stream.
+type SyntaxError struct {
+	Msg string // description of error
+	Pos uint   // token position in stream
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Msg
+}
+
 var errUnexpectedByte = errors.New("unexpected byte")
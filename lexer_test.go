@@ -5,6 +5,9 @@ package jsonlex
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -439,3 +442,222 @@ func TestLexer_Scan_11(t *testing.T) {
 		l.Scan(r)
 	}
 }
+
+// expect error when context is already done
+func TestLexer_ScanContext_1(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	i := 0
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		i++
+		if !kind.Is(TokenERR) || string(load) != context.Canceled.Error() {
+			t.Errorf("unexpected %q", load)
+		}
+		return true
+	}
+	l := NewLexer(y)
+	r := bytes.NewReader([]byte(`[1, 2, 3]`))
+	l.ScanContext(ctx, r)
+
+	if i != 1 {
+		t.Error("unexpected")
+	}
+}
+
+// expect error when context is cancelled while scanning
+func TestLexer_ScanContext_2(t *testing.T) {
+	s := "[" + strings.Repeat(`"foo", `, 1000) + `"bar"]`
+	ctx, cancel := context.WithCancel(context.Background())
+
+	i := 0
+	var last TokenKind
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		if i++; i == 10 {
+			cancel()
+		}
+		last = kind
+		return true
+	}
+	l := NewLexer(y)
+	r := bytes.NewReader([]byte(s))
+	l.ScanContext(ctx, r)
+
+	if !last.Is(TokenERR) {
+		t.Error("unexpected")
+	}
+	if i >= 2001 {
+		t.Error("unexpected")
+	}
+}
+
+// expect no interference when context is not done
+func TestLexer_ScanContext_3(t *testing.T) {
+	s := "[" + strings.Repeat(`"foo", `, 1000) + `"bar"]`
+
+	i := 0
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		i++
+		if kind.Is(TokenERR) {
+			t.Errorf("unexpected %q", load)
+		}
+		return true
+	}
+	l := NewLexer(y)
+	r := bytes.NewReader([]byte(s))
+	l.ScanContext(context.Background(), r)
+
+	if i != 2004 {
+		t.Errorf("unexpected %d", i)
+	}
+}
+
+// expect Err() to reveal the cause of a TokenERR
+func TestLexer_Err_1(t *testing.T) {
+	y := func(TokenKind, []byte, uint) bool { return true }
+	l := NewLexer(y)
+
+	l.Scan(bytes.NewReader([]byte(`[ * ]`)))
+	if e := (&SyntaxError{}); !errors.As(l.Err(), &e) || e.Pos != 2 {
+		t.Errorf("unexpected %v", l.Err())
+	}
+
+	l.Scan(&FaultyReader{})
+	if l.Err() != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected %v", l.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.ScanContext(ctx, bytes.NewReader([]byte(`[]`)))
+	if l.Err() != context.Canceled {
+		t.Errorf("unexpected %v", l.Err())
+	}
+
+	l.Scan(bytes.NewReader([]byte(`[]`)))
+	if l.Err() != nil {
+		t.Errorf("unexpected %v", l.Err())
+	}
+}
+
+// expect position of illegal value in error
+func TestLexer_Scan_12(t *testing.T) {
+	s := "[1, \x05]"
+
+	var pos uint
+	y := func(kind TokenKind, load []byte, p uint) bool {
+		pos = p
+		return true
+	}
+	l := NewLexer(y)
+	r := bytes.NewReader([]byte(s))
+	l.Scan(r)
+
+	if pos != 4 {
+		t.Errorf("unexpected %d", pos)
+	}
+}
+
+func TestLexer_Scan_13(t *testing.T) {
+	s := `{"a": ["b", {"c": 1, "d": [2.5, -3e2]}], "e": {}, "f": "g"} "h" 4`
+	e := "{ KEY:a : [ STR:b , { KEY:c : INT:1 , KEY:d : [ FLT:2.5 , FLT:-3e2 ] } ] , " +
+		"KEY:e : { } , KEY:f : STR:g } STR:h INT:4"
+
+	names := map[TokenKind]string{TokenKEY: "KEY:", TokenSTR: "STR:", TokenINT: "INT:", TokenFLT: "FLT:"}
+
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		if !kind.Is(TokenEOF) {
+			toks = append(toks, names[kind]+string(load))
+		}
+		return true
+	}
+	opts := []lexerOpt{LexerOptEmitKeys, LexerOptEmitNumberKinds}
+
+	toks = toks[:0]
+	NewLexer(y, opts...).Scan(bytes.NewReader([]byte(s)))
+	if a := strings.Join(toks, " "); a != e {
+		t.Errorf("unexpected %s", a)
+	}
+
+	toks = toks[:0]
+	NewLexer(y, opts...).ScanIndexed([]byte(s))
+	if a := strings.Join(toks, " "); a != e {
+		t.Errorf("unexpected %s", a)
+	}
+
+	toks = toks[:0]
+	NewLexer(y, opts...).ScanParallel([]byte(s), 4)
+	if a := strings.Join(toks, " "); a != e {
+		t.Errorf("unexpected %s", a)
+	}
+
+	toks = toks[:0]
+	NewLexer(y).Scan(bytes.NewReader([]byte(`{"a": 1.5}`)))
+	if a := strings.Join(toks, " "); a != "{ STR:a : 1.5 }" {
+		t.Errorf("unexpected %s", a)
+	}
+}
+
+func TestLexer_Depth_1(t *testing.T) {
+	s := `{"a": [1, {"b": null}], "c": 2} [3]`
+	e := "{0 a1o :1o [1o 12a ,2a {2a b3o :3o null3o }2a ]1o ,1o c1o :1o 21o }0 [0 31a ]0 0"
+
+	var l *Lexer
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		c := ""
+		if l.InObject() {
+			c = "o"
+		}
+		if l.InArray() {
+			c = "a"
+		}
+		toks = append(toks, fmt.Sprintf("%s%d%s", load, l.Depth(), c))
+		return true
+	}
+	l = NewLexer(y, LexerOptTrackDepth)
+	l.Scan(bytes.NewReader([]byte(s)))
+
+	if a := strings.Join(toks, " "); a != e {
+		t.Errorf("unexpected %s", a)
+	}
+
+	toks = toks[:0]
+	l.ScanIndexed([]byte(s))
+	if a := strings.Join(toks, " "); a != e {
+		t.Errorf("unexpected %s", a)
+	}
+
+	// no tracking without options
+	toks = toks[:0]
+	l = NewLexer(y)
+	l.Scan(bytes.NewReader([]byte(s)))
+	if a := strings.Join(toks, " "); a != "{0 a0 :0 [0 10 ,0 {0 b0 :0 null0 }0 ]0 ,0 c0 :0 20 }0 [0 30 ]0 0" {
+		t.Errorf("unexpected %s", a)
+	}
+}
+
+// expect an unterminated string to be an error
+func TestLexer_Scan_14(t *testing.T) {
+	for s, e := range map[string]uint{`"abc`: 0, `["a", "b`: 6, `"`: 0, `{"a\"`: 1} {
+		var last TokenKind
+		var pos uint
+		y := func(kind TokenKind, load []byte, p uint) bool {
+			last, pos = kind, p
+			return true
+		}
+		for _, scan := range []func(l *Lexer){
+			func(l *Lexer) { l.Scan(bytes.NewReader([]byte(s))) },
+			func(l *Lexer) { l.ScanIndexed([]byte(s)) },
+			func(l *Lexer) { l.ScanParallel([]byte(s), 2) },
+			func(l *Lexer) { _, _ = l.Write([]byte(s)); _ = l.Close() },
+		} {
+			l := NewLexer(y)
+			scan(l)
+			if err := l.Err(); err == nil || err.Error() != "unterminated string" || !last.Is(TokenERR) || pos != e {
+				t.Errorf("unexpected %v %d for %s", err, pos, s)
+			}
+		}
+	}
+}
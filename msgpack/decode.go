@@ -0,0 +1,255 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package msgpack
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/codec"
+)
+
+type (
+	// Tokenizer reads MessagePack objects and yields the tokens of the
+	// equivalent JSON text, so that code consuming the token stream of
+	// the jsonlex.Lexer works on MessagePack input unchanged. Strings
+	// are yielded escaped like the string loads of the Lexer, binary
+	// data base64 encoded. Integers and floats are yielded as
+	// jsonlex.TokenNUM. Extension types are not supported, map keys
+	// must be strings. A sequence of objects results in a stream of
+	// concatenated values.
+	Tokenizer struct {
+		yield jsonlex.Yield
+		r     io.Reader
+		stack []frame
+		off   uint // number of bytes consumed
+		err   error
+		num   [8]byte
+		load  []byte
+		str   []byte
+	}
+
+	// frame is an open map or array.
+	frame struct {
+		obj  bool
+		left uint64 // number of keys, values or elements left
+		n    uint64 // number of keys, values or elements read
+		sep  bool   // separator yielded after the n-th item
+	}
+)
+
+// NewTokenizer creates a Tokenizer, the yield function
+// will be invoked for each token consumed by Scan().
+func NewTokenizer(yield jsonlex.Yield) *Tokenizer {
+	return &Tokenizer{yield: yield}
+}
+
+// NewCursor creates a jsonlex.Cursor over MessagePack input.
+func NewCursor(r io.Reader, f jsonlex.Filter) *jsonlex.Cursor {
+	return jsonlex.NewCursor(r, f, jsonlex.CursorOptTokenizer(
+		func(yield jsonlex.Yield) jsonlex.Tokenizer { return NewTokenizer(yield) },
+	))
+}
+
+// Scan reads objects from r and invokes the yield function for each
+// token, until the yield function returns false or a jsonlex.TokenEOF
+// or jsonlex.TokenERR has been yielded. Scan() is reentrant, subsequent
+// invocations continue with the next token. Like the Lexer, the Tokenizer
+// reads byte by byte, wrap your reader with bufio.Reader or use bytes.Reader.
+func (t *Tokenizer) Scan(r io.Reader) {
+	t.r = r
+	for {
+		kind, load, pos := t.next()
+		if !t.yield(kind, load, pos) || kind == jsonlex.TokenEOF || kind == jsonlex.TokenERR {
+			return
+		}
+	}
+}
+
+// Err returns the error which caused the jsonlex.TokenERR, nil
+// otherwise. The error is either a *jsonlex.SyntaxError or an
+// error reported by the io.Reader. Errors are sticky.
+func (t *Tokenizer) Err() error {
+	return t.err
+}
+
+// Offset returns the number of bytes consumed.
+func (t *Tokenizer) Offset() uint {
+	return t.off
+}
+
+func (t *Tokenizer) next() (jsonlex.TokenKind, []byte, uint) {
+	if t.err != nil {
+		return jsonlex.TokenERR, []byte(t.err.Error()), t.off
+	}
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		if f.left == 0 {
+			t.stack = t.stack[:n-1]
+			if f.obj {
+				return jsonlex.TokenRCB, append(t.load[:0], '}'), t.off
+			}
+			return jsonlex.TokenRSB, append(t.load[:0], ']'), t.off
+		}
+		if f.n > 0 && !f.sep {
+			f.sep = true
+			if f.obj && f.n%2 == 1 {
+				return jsonlex.TokenCOL, append(t.load[:0], ':'), t.off
+			}
+			return jsonlex.TokenCOM, append(t.load[:0], ','), t.off
+		}
+	}
+	return t.item()
+}
+
+func (t *Tokenizer) item() (jsonlex.TokenKind, []byte, uint) {
+	pos, key := t.off, false
+	if n := len(t.stack); n > 0 {
+		f := &t.stack[n-1]
+		key = f.obj && f.n%2 == 0
+		f.n, f.sep, f.left = f.n+1, false, f.left-1
+	}
+
+	u, err := t.readUint(1)
+	if err == io.EOF && len(t.stack) == 0 {
+		return jsonlex.TokenEOF, nil, pos
+	}
+	if err != nil {
+		return t.fail(err)
+	}
+	b := byte(u)
+
+	if isString := b >= 0xA0 && b <= 0xBF || b >= 0xC4 && b <= 0xC6 || b >= 0xD9 && b <= 0xDB; key && !isString {
+		return t.syntax(pos, "unsupported map key")
+	}
+
+	switch {
+	case b <= 0x7F:
+		return jsonlex.TokenNUM, strconv.AppendUint(t.load[:0], u, 10), pos
+	case b <= 0x8F:
+		return t.open(true, uint64(b&0x0F), pos)
+	case b <= 0x9F:
+		return t.open(false, uint64(b&0x0F), pos)
+	case b <= 0xBF:
+		return t.readString(true, uint64(b&0x1F), pos)
+	case b >= 0xE0:
+		return jsonlex.TokenNUM, strconv.AppendInt(t.load[:0], int64(int8(b)), 10), pos
+	}
+
+	switch b {
+	case 0xC0:
+		return jsonlex.TokenLIT, append(t.load[:0], "null"...), pos
+	case 0xC2:
+		return jsonlex.TokenLIT, append(t.load[:0], "false"...), pos
+	case 0xC3:
+		return jsonlex.TokenLIT, append(t.load[:0], "true"...), pos
+	case 0xC7, 0xC8, 0xC9, 0xD4, 0xD5, 0xD6, 0xD7, 0xD8:
+		return t.syntax(pos, "unsupported extension type")
+	case 0xC1:
+		return t.syntax(pos, "unexpected byte 0x%02x", b)
+	}
+
+	// the remaining formats are followed by a 1, 2, 4 or 8 byte argument
+	size := [...]int{
+		0xC4: 1, 0xC5: 2, 0xC6: 4, // bin
+		0xCA: 4, 0xCB: 8, // float
+		0xCC: 1, 0xCD: 2, 0xCE: 4, 0xCF: 8, // uint
+		0xD0: 1, 0xD1: 2, 0xD2: 4, 0xD3: 8, // int
+		0xD9: 1, 0xDA: 2, 0xDB: 4, // str
+		0xDC: 2, 0xDD: 4, // array
+		0xDE: 2, 0xDF: 4, // map
+	}[b]
+	if u, err = t.readUint(size); err != nil {
+		return t.fail(err)
+	}
+
+	switch {
+	case b <= 0xC6:
+		return t.readString(false, u, pos)
+	case b == 0xCA:
+		return t.float(float64(math.Float32frombits(uint32(u))), pos)
+	case b == 0xCB:
+		return t.float(math.Float64frombits(u), pos)
+	case b <= 0xCF:
+		return jsonlex.TokenNUM, strconv.AppendUint(t.load[:0], u, 10), pos
+	case b <= 0xD3:
+		shift := 64 - 8*uint(size)
+		return jsonlex.TokenNUM, strconv.AppendInt(t.load[:0], int64(u<<shift)>>shift, 10), pos
+	case b <= 0xDB:
+		return t.readString(true, u, pos)
+	case b <= 0xDD:
+		return t.open(false, u, pos)
+	}
+	return t.open(true, u, pos)
+}
+
+// open pushes a map or array of n entries.
+func (t *Tokenizer) open(obj bool, n uint64, pos uint) (jsonlex.TokenKind, []byte, uint) {
+	if obj {
+		t.stack = append(t.stack, frame{obj: true, left: 2 * n})
+		return jsonlex.TokenLCB, append(t.load[:0], '{'), pos
+	}
+	t.stack = append(t.stack, frame{left: n})
+	return jsonlex.TokenLSB, append(t.load[:0], '['), pos
+}
+
+// readString reads a string or binary data of length n.
+func (t *Tokenizer) readString(text bool, n uint64, pos uint) (jsonlex.TokenKind, []byte, uint) {
+	if err := t.readN(n); err != nil {
+		return t.fail(err)
+	}
+	if !text {
+		t.load = append(t.load[:0], base64.StdEncoding.EncodeToString(t.str)...)
+		return jsonlex.TokenSTR, t.load, pos
+	}
+	if !utf8.Valid(t.str) {
+		return t.syntax(pos, "invalid UTF-8 in string")
+	}
+	t.load = jsonlex.Escape(t.load[:0], string(t.str))
+	return jsonlex.TokenSTR, t.load, pos
+}
+
+func (t *Tokenizer) float(f float64, pos uint) (jsonlex.TokenKind, []byte, uint) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return t.syntax(pos, "unsupported value %v", f)
+	}
+	t.load = codec.AppendFloat(t.load[:0], f)
+	return jsonlex.TokenNUM, t.load, pos
+}
+
+// readUint reads a big endian unsigned integer of n bytes.
+func (t *Tokenizer) readUint(n int) (u uint64, err error) {
+	if _, err = io.ReadFull(t.r, t.num[:n]); err != nil {
+		return 0, err
+	}
+	t.off += uint(n)
+	for _, b := range t.num[:n] {
+		u = u<<8 | uint64(b)
+	}
+	return u, nil
+}
+
+// readN reads n bytes into t.str and counts them in t.off.
+func (t *Tokenizer) readN(n uint64) (err error) {
+	var m int
+	t.str, m, err = codec.ReadN(t.r, t.str[:0], n)
+	t.off += uint(m)
+	return err
+}
+
+func (t *Tokenizer) syntax(pos uint, format string, args ...interface{}) (jsonlex.TokenKind, []byte, uint) {
+	return t.fail(&jsonlex.SyntaxError{Msg: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+func (t *Tokenizer) fail(err error) (jsonlex.TokenKind, []byte, uint) {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: t.off}
+	}
+	t.err = err
+	return jsonlex.TokenERR, []byte(err.Error()), t.off
+}
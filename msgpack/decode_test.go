@@ -0,0 +1,118 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func tokenize(t *testing.T, h string) (string, error) {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	var toks []string
+	tok := NewTokenizer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		if kind != jsonlex.TokenEOF && kind != jsonlex.TokenERR {
+			toks = append(toks, string(load))
+		}
+		return true
+	})
+	tok.Scan(bytes.NewReader(b))
+	return strings.Join(toks, " "), tok.Err()
+}
+
+func TestTokenizer_Scan_1(t *testing.T) {
+	for h, e := range map[string]string{
+		"00": "0", "7f": "127", "cc80": "128", "cd0100": "256", "ce00010000": "65536", "cfffffffffffffffff": "18446744073709551615",
+		"ff": "-1", "e0": "-32", "d0df": "-33", "d1ff7f": "-129", "d2ffff7fff": "-32769", "d38000000000000000": "-9223372036854775808",
+		"ca3fc00000": "1.5", "cb3fb999999999999a": "0.1", "ca80000000": "-0", "ca5f800000": "18446744073709552000",
+		"cb4415af1d78b58c40": "100000000000000000000", "cb3eb0c6f7a0b5ed8d": "0.000001", "cb3e7ad7f29abcaf48": "1e-7",
+		"c3": "true", "c2": "false", "c0": "null", "a0": "", "a361c3bc": "aü", "a2225c": `\"\\`, "d90161": "a", "c403010203": "AQID",
+		"90": "[ ]", "80": "{ }", "930192020381a161c0": "[ 1 , [ 2 , 3 ] , { a : null } ]",
+		"82a16181a16291c3a16301": "{ a : { b : [ true ] } , c : 1 }", "019102": "1 [ 2 ]",
+		"dc000201c2": "[ 1 , false ]", "de0001a0a0": "{  :  }",
+	} {
+		if r, err := tokenize(t, h); err != nil || r != e {
+			t.Errorf("unexpected %q %v for %s", r, err, h)
+		}
+	}
+}
+
+func TestTokenizer_Scan_2(t *testing.T) {
+	for _, h := range []string{
+		"cc", "92", "81a161", "8101c0", "c1", "d40100", "c70101ff", "ca7f800000", "cb7ff8000000000000",
+		"a2c328", "a36162",
+	} {
+		if _, err := tokenize(t, h); err == nil {
+			t.Errorf("unexpected nil error %s", h)
+		}
+	}
+	_, err := tokenize(t, "9201c1")
+	if e, ok := err.(*jsonlex.SyntaxError); !ok || e.Pos != 2 {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestTokenizer_Scan_3(t *testing.T) {
+	for _, s := range []string{
+		`{"a": [1, -2.5, 1e+21, "x\"y\u0001", true, null], "b": {}, "c": []}`,
+		`[-9223372036854775808, 0.1, {"": [[]]}] "ü" 42`,
+		"[" + strings.Repeat(`"`+strings.Repeat("x", 300)+`",`, 20) + "70000]",
+	} {
+		buf := bytes.Buffer{}
+		if err := NewEncoder(&buf).Encode(strings.NewReader(s)); err != nil {
+			t.Fatalf("unexpected %s", err)
+		}
+		var a, b []string
+		jsonlex.NewLexer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+			a = append(a, string(load))
+			return true
+		}).Scan(strings.NewReader(s))
+
+		c := NewCursor(&buf, nil)
+		for c.Scan() {
+			b = append(b, c.Curr().String())
+		}
+		if c.Err() != nil || strings.Join(a[:len(a)-1], " ") != strings.Join(b, " ") {
+			t.Errorf("unexpected %v %v", b, c.Err())
+		}
+	}
+}
+
+func TestTokenizer_Scan_4(t *testing.T) {
+	var toks []string
+	tok := NewTokenizer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, string(load))
+		return false
+	})
+	r := bytes.NewReader([]byte{0x92, 0x01, 0x02})
+	for i := 0; i < 6; i++ {
+		tok.Scan(r)
+	}
+	if strings.Join(toks, " ") != "[ 1 , 2 ] " || tok.Offset() != 3 || tok.Err() != nil {
+		t.Errorf("unexpected %q", toks)
+	}
+}
+
+// decoded floats parse to the same float64
+func TestTokenizer_Scan_5(t *testing.T) {
+	for h, e := range map[string]float64{
+		"ca5f800000": 1.8446744073709552e19, "cadf800000": -18446744073709551616, "ca33800000": 5.960464477539063e-8,
+		"ca7f7fffff": 3.4028234663852886e+38, "ca3dcccccd": 0.10000000149011612, "cb3fb999999999999a": 0.1,
+	} {
+		r, err := tokenize(t, h)
+		if err != nil {
+			t.Fatalf("unexpected %s", err)
+		}
+		if f, err := strconv.ParseFloat(r, 64); err != nil || f != e {
+			t.Errorf("unexpected %s for %s", r, h)
+		}
+	}
+}
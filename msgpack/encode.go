@@ -0,0 +1,274 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package msgpack converts between the token stream of the
+// jsonlex.Lexer and MessagePack.
+//
+// MessagePack requires the sizes of maps and arrays up front, so the
+// Encoder keeps each top level map or array in memory until it is
+// complete. Streams of many small top level values are encoded with
+// little memory, a single large document is held entirely.
+package msgpack
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/number"
+)
+
+type (
+	// Encoder writes the tokens of a JSON stream as MessagePack objects.
+	// Top level scalars are written immediately, maps and arrays are
+	// buffered until the top level value is complete. The structure of
+	// the token stream is validated. Number lexemes which denote an
+	// integer in the range of int64 or uint64, like 100, 1e2 or 100.0,
+	// are encoded as the smallest integer. Other numbers, -0.0 and
+	// integers exceeding 64 bits are rounded to the nearest float64,
+	// which is encoded as the smallest float (32 or 64 bit) holding it
+	// exactly. Digits beyond float64 precision are lost.
+	Encoder struct {
+		w     *bufio.Writer
+		g     jsonlex.Grammar
+		stack []int  // indices of the open containers in heads
+		heads []head // container heads of the top level value
+		body  []byte // top level value without container heads
+		err   error
+		buf   []byte
+		str   []byte
+	}
+
+	// head is inserted into the body at off when writing.
+	head struct {
+		off int
+		obj bool
+		n   int // number of member names and values or elements
+		b   [5]byte
+		len int
+	}
+)
+
+// NewEncoder creates an Encoder.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode reads the JSON stream from r and writes its values. A
+// stream of concatenated values results in a sequence of objects.
+func (e *Encoder) Encode(r io.Reader) error {
+	l := jsonlex.NewLexer(e.Yield)
+	l.Scan(r)
+
+	if err := l.Err(); err != nil {
+		return err
+	}
+	return e.Flush()
+}
+
+// Yield consumes a token, it is a jsonlex.Yield function.
+// Buffered data is flushed on jsonlex.TokenEOF.
+func (e *Encoder) Yield(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+	if e.err != nil {
+		return false
+	}
+
+	switch kind {
+	case jsonlex.TokenEOF:
+		if !e.g.Done() {
+			e.err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: pos}
+		}
+		_ = e.Flush()
+		return false
+	case jsonlex.TokenERR:
+		e.err = errors.New(string(load))
+		return false
+	}
+	if _, ok := e.g.Push(kind); !ok {
+		e.err = &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+		return false
+	}
+
+	switch kind {
+	case jsonlex.TokenCOL, jsonlex.TokenCOM:
+		return true
+	case jsonlex.TokenRCB, jsonlex.TokenRSB:
+		n := len(e.stack)
+		h := &e.heads[e.stack[n-1]]
+		e.stack = e.stack[:n-1]
+
+		// the heads fit into h.b, appending does not reallocate
+		if h.obj {
+			h.len = len(appendMapHead(h.b[:0], h.n/2))
+		} else {
+			h.len = len(appendArrayHead(h.b[:0], h.n))
+		}
+		if n == 1 {
+			e.writeValue()
+		}
+		return e.err == nil
+	}
+
+	if n := len(e.stack); n > 0 {
+		e.heads[e.stack[n-1]].n++
+	}
+
+	b := e.buf[:0]
+	switch kind {
+	case jsonlex.TokenLCB, jsonlex.TokenLSB:
+		e.stack = append(e.stack, len(e.heads))
+		e.heads = append(e.heads, head{off: len(e.body), obj: kind == jsonlex.TokenLCB})
+		return true
+	case jsonlex.TokenSTR, jsonlex.TokenKEY:
+		s, err := jsonlex.Unescape(e.str[:0], load)
+		if err != nil {
+			e.err = &jsonlex.SyntaxError{Msg: err.Error(), Pos: pos}
+			return false
+		}
+		e.str = s
+		b = appendStringHead(b, len(s))
+		b = append(b, s...)
+	case jsonlex.TokenLIT:
+		switch load[0] {
+		case 'f':
+			b = append(b, 0xC2)
+		case 't':
+			b = append(b, 0xC3)
+		default:
+			b = append(b, 0xC0)
+		}
+	default: // numbers
+		var err error
+		if b, err = appendNumber(b, load); err != nil {
+			e.err = &jsonlex.SyntaxError{Msg: err.Error(), Pos: pos}
+			return false
+		}
+	}
+
+	e.buf = b
+	if len(e.stack) > 0 {
+		e.body = append(e.body, b...)
+	} else {
+		_, e.err = e.w.Write(b)
+	}
+	return e.err == nil
+}
+
+// Flush writes buffered data to the underlying io.Writer.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.w.Flush()
+	return e.err
+}
+
+// writeValue writes the completed top level map or array. The heads
+// are in document order, an enclosing head precedes the nested ones.
+func (e *Encoder) writeValue() {
+	off := 0
+	for i := range e.heads {
+		h := &e.heads[i]
+		if e.err == nil {
+			_, e.err = e.w.Write(e.body[off:h.off])
+		}
+		if e.err == nil {
+			_, e.err = e.w.Write(h.b[:h.len])
+		}
+		off = h.off
+	}
+	if e.err == nil {
+		_, e.err = e.w.Write(e.body[off:])
+	}
+	e.heads, e.body = e.heads[:0], e.body[:0]
+}
+
+func appendNumber(b []byte, load []byte) ([]byte, error) {
+	if s, ok := number.Integral(load); ok {
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return appendUint(b, u), nil
+		}
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return appendInt(b, i), nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(string(load), 64)
+	if err != nil {
+		return nil, fmt.Errorf("number %s out of range", load)
+	}
+	if f32 := float32(f); float64(f32) == f {
+		u := math.Float32bits(f32)
+		return append(b, 0xCA, byte(u>>24), byte(u>>16), byte(u>>8), byte(u)), nil
+	}
+	u := math.Float64bits(f)
+	return append(b, 0xCB, byte(u>>56), byte(u>>48), byte(u>>40), byte(u>>32),
+		byte(u>>24), byte(u>>16), byte(u>>8), byte(u)), nil
+}
+
+func appendUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= 0x7F:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xCC, byte(u))
+	case u <= math.MaxUint16:
+		return append(b, 0xCD, byte(u>>8), byte(u))
+	case u <= math.MaxUint32:
+		return append(b, 0xCE, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+	}
+	return append(b, 0xCF, byte(u>>56), byte(u>>48), byte(u>>40), byte(u>>32),
+		byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+}
+
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xD0, byte(i))
+	case i >= math.MinInt16:
+		return append(b, 0xD1, byte(i>>8), byte(i))
+	case i >= math.MinInt32:
+		return append(b, 0xD2, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+	}
+	return append(b, 0xD3, byte(i>>56), byte(i>>48), byte(i>>40), byte(i>>32),
+		byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+func appendStringHead(b []byte, n int) []byte {
+	switch {
+	case n <= 31:
+		return append(b, 0xA0|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, 0xD9, byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xDA, byte(n>>8), byte(n))
+	}
+	return append(b, 0xDB, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendArrayHead(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xDC, byte(n>>8), byte(n))
+	}
+	return append(b, 0xDD, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendMapHead(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return append(b, 0xDE, byte(n>>8), byte(n))
+	}
+	return append(b, 0xDF, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
@@ -0,0 +1,77 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func encode(t *testing.T, s string) string {
+	buf := bytes.Buffer{}
+	if err := NewEncoder(&buf).Encode(strings.NewReader(s)); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestEncoder_Encode_1(t *testing.T) {
+	for s, e := range map[string]string{
+		`0`: "00", `127`: "7f", `128`: "cc80", `256`: "cd0100", `65536`: "ce00010000",
+		`4294967296`: "cf0000000100000000", `18446744073709551615`: "cfffffffffffffffff",
+		`-1`: "ff", `-32`: "e0", `-33`: "d0df", `-129`: "d1ff7f", `-32769`: "d2ffff7fff",
+		`-2147483649`: "d3ffffffff7fffffff", `-0`: "00", `18446744073709551616`: "ca5f800000",
+		`1.5`: "ca3fc00000", `0.1`: "cb3fb999999999999a", `-0.0`: "ca80000000", `1e2`: "64", `-1.5e1`: "f1",
+		`1.8446744073709551616e19`: "ca5f800000", `true`: "c3", `false`: "c2", `null`: "c0", `""`: "a0",
+		`"aü"`: "a361c3bc", `"` + strings.Repeat("x", 32) + `"`: "d920" + strings.Repeat("78", 32),
+		`[]`: "90", `{}`: "80", `[1, [2, 3], {"a": null}]`: "9301920203" + "81a161c0",
+		`{"a": {"b": [true]}, "c": 1}`: "82a16181a16291c3a16301", `1 [2]`: "019102",
+		`[[[]], {"a": [{}]}] [] 1`: "92919081a161918090" + "01",
+	} {
+		if r := encode(t, s); r != e {
+			t.Errorf("unexpected %s for %s", r, s)
+		}
+	}
+}
+
+func TestEncoder_Encode_2(t *testing.T) {
+	s := "[" + strings.Repeat("1,", 15) + "1]"
+	if r := encode(t, s); r != "dc0010"+strings.Repeat("01", 16) {
+		t.Errorf("unexpected %s", r)
+	}
+	for _, s := range []string{`[1`, `]`, `[}`, `1e400`, `"\x"`, `@`, `{"a"}`, `{1:2}`, `[1 2]`, `[1,]`, `{"a":1,}`, `1 ,`} {
+		if err := NewEncoder(&bytes.Buffer{}).Encode(strings.NewReader(s)); err == nil {
+			t.Errorf("unexpected nil error %s", s)
+		}
+	}
+}
+
+func TestEncoder_Yield_1(t *testing.T) {
+	buf := bytes.Buffer{}
+	e := NewEncoder(&buf)
+
+	l := jsonlex.NewLexer(e.Yield, jsonlex.LexerOptEmitKeys, jsonlex.LexerOptEmitNumberKinds)
+	l.Scan(strings.NewReader(`{"a": [1.5, 2]}`))
+
+	if e.Flush() != nil || hex.EncodeToString(buf.Bytes()) != "81a16192ca3fc0000002" {
+		t.Errorf("unexpected %x", buf.Bytes())
+	}
+}
+
+func TestEncoder_Yield_2(t *testing.T) {
+	for _, s := range []string{`{"a"}`, `{1:2}`, `[1 2]`, `{"a":1 "b":2}`, `[1:2]`} {
+		buf := bytes.Buffer{}
+		e := NewEncoder(&buf)
+		jsonlex.NewLexer(e.Yield, jsonlex.LexerOptEmitKeys).Scan(strings.NewReader(s))
+
+		var err *jsonlex.SyntaxError
+		if !errors.As(e.Flush(), &err) {
+			t.Errorf("unexpected %v for %s", e.Flush(), s)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// parallel chunk of the buffer and its tokens
+	chunk struct {
+		from, to int
+		toks     []chunkTok
+		err      error
+		done     chan struct{}
+	}
+
+	chunkTok struct {
+		kind TokenKind
+		pos  uint32 // relative to chunk
+		len  uint32
+	}
+
+	// string state at a chunk boundary
+	quoteState struct {
+		odd bool // odd number of unescaped quotes
+		esc bool // ends with escaping backslash
+	}
+)
+
+// ScanParallel tokenizes a complete in-memory buffer using the given
+// number of concurrent workers. The buffer is split into chunks, the
+// string state at chunk boundaries is resolved by a concurrent quote
+// and escape pre-pass. The yield function is invoked for each token in
+// order of occurrence with positions relative to the buffer start, the
+// loads reference the buffer. At most one chunk per worker is scanned
+// ahead of the yield function, so the memory for tokens is bounded by
+// the chunk size, not by the buffer size. ScanParallel terminates like
+// Scan() but is not reentrant, small buffers are scanned sequentially.
+func (l *Lexer) ScanParallel(data []byte, workers int) {
+	l.reset()
+
+	if workers < 2 || len(data) < 2*minChunkSize {
+		l.Scan(bytes.NewReader(data))
+		return
+	}
+
+	chunks := splitChunks(data, workers)
+	stop := int32(0)
+	quit := make(chan struct{})
+	defer func() {
+		atomic.StoreInt32(&stop, 1)
+		close(quit)
+	}()
+
+	// a slot is taken before a chunk is queued and
+	// released after its tokens have been yielded
+	slots := make(chan struct{}, workers)
+	queue := make(chan *chunk)
+	go func() {
+		defer close(queue)
+		for _, c := range chunks {
+			select {
+			case slots <- struct{}{}:
+			case <-quit:
+				return
+			}
+			queue <- c
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for c := range queue {
+				c.scan(data, &stop)
+				close(c.done)
+			}
+		}()
+	}
+
+	for _, c := range chunks {
+		<-c.done
+		ok := l.deliver(data, c)
+		c.toks = nil
+		<-slots
+		if !ok {
+			return
+		}
+	}
+}
+
+// deliver yields the tokens of a chunk and reports whether to continue.
+func (l *Lexer) deliver(data []byte, c *chunk) bool {
+	for _, t := range c.toks {
+		pos := uint(c.from) + uint(t.pos)
+		l.tpos, l.bpos = pos, uint(c.from)+uint(t.pos+t.len)
+
+		switch t.kind {
+		case TokenERR:
+			l.err = c.err
+			if e, ok := c.err.(*SyntaxError); ok {
+				l.err = &SyntaxError{Msg: e.Msg, Pos: pos}
+			}
+			l.yield(TokenERR, []byte(l.err.Error()), pos)
+			return false
+		case TokenEOF:
+			l.yield(TokenEOF, nil, pos)
+			return false
+		case TokenSTR:
+			if !l.emit(t.kind, data[pos+1:pos+1+uint(t.len)], pos) {
+				return false
+			}
+		default:
+			if !l.emit(t.kind, data[pos:pos+uint(t.len)], pos) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scan tokenizes a chunk, positions are relative to the chunk.
+func (c *chunk) scan(data []byte, stop *int32) {
+	var l *Lexer
+	last := c.to == len(data)
+
+	l = NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenEOF) && !last {
+			return false
+		}
+		if kind.Is(TokenERR) {
+			c.err = l.Err()
+		}
+		c.toks = append(c.toks, chunkTok{kind, uint32(pos), uint32(len(load))})
+		return atomic.LoadInt32(stop) == 0
+	})
+	l.Scan(bytes.NewReader(data[c.from:c.to]))
+}
+
+// splitChunks splits the buffer at token boundaries. The string state at
+// nominal split points is resolved by a concurrent pre-pass, then each
+// split point is moved behind the next structural character outside
+// of strings.
+func splitChunks(data []byte, workers int) []*chunk {
+	n := workers * chunksPerWorker
+	if limit := len(data) / maxChunkSize; n < limit {
+		n = limit
+	}
+	if limit := len(data) / minChunkSize; n > limit {
+		n = limit
+	}
+	if limit := len(data)>>31 + 1; n < limit {
+		n = limit // positions of chunkTok are 32 bit
+	}
+	size := len(data) / n
+
+	// pre-pass for both possible escape states at chunk start
+	states := make([][2]quoteState, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			part := data[i*size : nominalEnd(i, n, size, len(data))]
+			states[i][0] = scanQuotes(part, false)
+			states[i][1] = scanQuotes(part, true)
+		}(i)
+	}
+	wg.Wait()
+
+	chunks := make([]*chunk, 0, n)
+	from, str, esc := 0, false, false
+
+	for i := 0; i < n; i++ {
+		s := states[i][0]
+		if esc {
+			s = states[i][1]
+		}
+		str, esc = str != s.odd, s.esc
+
+		to := len(data)
+		if i < n-1 {
+			to = splitPoint(data, nominalEnd(i, n, size, len(data)), str, esc)
+		}
+		if to > from {
+			chunks = append(chunks, &chunk{from: from, to: to, done: make(chan struct{})})
+			from = to
+		}
+	}
+	return chunks
+}
+
+func nominalEnd(i, n, size, total int) int {
+	if i == n-1 {
+		return total
+	}
+	return (i + 1) * size
+}
+
+// scanQuotes determines the quote parity and escape state at the end of
+// a part of the buffer. Like in simdjson, backslashes are interpreted
+// regardless of string state, since they are invalid outside strings.
+func scanQuotes(part []byte, esc bool) quoteState {
+	odd := false
+	for _, b := range part {
+		switch {
+		case esc:
+			esc = false
+		case b == '\\':
+			esc = true
+		case b == '"':
+			odd = !odd
+		}
+	}
+	return quoteState{odd: odd, esc: esc}
+}
+
+// splitPoint returns the position behind the first structural character
+// outside of strings, starting with the given state at position i.
+func splitPoint(data []byte, i int, str, esc bool) int {
+	for ; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case esc:
+			esc = false
+		case b == '\\':
+			esc = true
+		case b == '"':
+			str = !str
+		case !str && (b == ',' || b == ':' || b == '[' || b == ']' || b == '{' || b == '}'):
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+func (l *Lexer) reset() {
+	l.bpos, l.tpos = 0, 0
+	l.hold, l.burd, l.susp = false, false, false
+	l.nest, l.prev = l.nest[:0], TokenEOF
+	l.err = nil
+}
+
+const (
+	minChunkSize    = 64 << 10
+	maxChunkSize    = 4 << 20
+	chunksPerWorker = 4
+)
@@ -0,0 +1,117 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parallelTokens(data []byte, workers int) []string {
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, fmt.Sprintf("%d:%d:%s", pos, kind, load))
+		return true
+	}
+	l := NewLexer(y)
+	if workers < 0 {
+		l.Scan(bytes.NewReader(data))
+	} else {
+		l.ScanParallel(data, workers)
+	}
+	return toks
+}
+
+// expect the same tokens as Scan()
+func TestLexer_ScanParallel_1(t *testing.T) {
+	doc, err := ioutil.ReadFile("testdata/200kB.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// long strings with escapes spanning chunk boundaries
+	esc := `["` + strings.Repeat(`\\\"a\\\\ , {\"x\": [1]}`, 20000) + `", 1.5, "\\", {"a": ["\""]}]`
+
+	for _, data := range [][]byte{doc, []byte(esc), []byte(`[]`), nil} {
+		e := parallelTokens(data, -1)
+
+		for _, workers := range []int{1, 2, 3, 8} {
+			if toks := parallelTokens(data, workers); fmt.Sprint(toks) != fmt.Sprint(e) {
+				t.Errorf("%d: unexpected", workers)
+			}
+		}
+	}
+}
+
+// expect errors with correct positions
+func TestLexer_ScanParallel_2(t *testing.T) {
+	data := []byte(strings.Repeat(`{"a": [1, true, "x"]},`, 20000) + `[1, 2.e, 3]`)
+
+	var (
+		last TokenKind
+		pos  uint
+		i    int
+	)
+	l := NewLexer(func(kind TokenKind, load []byte, p uint) bool {
+		last, pos = kind, p
+		i++
+		return true
+	})
+	l.ScanParallel(data, 4)
+
+	if !last.Is(TokenERR) || pos != uint(len(data)-7) {
+		t.Errorf("unexpected %d %d", last, pos)
+	}
+	if e, ok := l.Err().(*SyntaxError); !ok || e.Pos != pos {
+		t.Errorf("unexpected %v", l.Err())
+	}
+	if i != 20000*12+4 {
+		t.Errorf("unexpected %d", i)
+	}
+}
+
+// expect yield to stop the process
+func TestLexer_ScanParallel_3(t *testing.T) {
+	data := []byte(strings.Repeat(`[1, 2, 3],`, 100000))
+
+	i := 0
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		i++
+		return i < 100
+	})
+	n := runtime.NumGoroutine()
+	l.ScanParallel(data, 4)
+
+	if i != 100 {
+		t.Errorf("unexpected %d", i)
+	}
+	// expect the feeder and the workers to terminate
+	for k := 0; k < 100 && runtime.NumGoroutine() > n; k++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if runtime.NumGoroutine() > n {
+		t.Errorf("unexpected %d goroutines", runtime.NumGoroutine()-n)
+	}
+}
+
+// expect chunks of bounded size for large buffers
+func TestSplitChunks_1(t *testing.T) {
+	data := []byte(strings.Repeat(`[1, 2, 3],`, 2<<20))
+
+	chunks := splitChunks(data, 2)
+	if len(chunks) < len(data)/maxChunkSize {
+		t.Errorf("unexpected %d", len(chunks))
+	}
+	i := 0
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		i++
+		return true
+	})
+	if l.ScanParallel(data, 2); i != 2<<20*8+1 || l.Err() != nil {
+		t.Errorf("unexpected %d %v", i, l.Err())
+	}
+}
@@ -0,0 +1,72 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"errors"
+	"io"
+)
+
+// Write feeds a chunk of the byte stream into the Lexer (push mode).
+// The yield function is invoked for each token completed by the chunk,
+// partial tokens are kept across chunk boundaries. The Lexer implements
+// io.Writer, so it can be the target of io.Copy() for example.
+//
+// When the yield function returns false, Write returns early with
+// io.ErrShortWrite, the remaining bytes p[n:] can be written again.
+// After a jsonlex.TokenERR, Write fails with the cause of the error.
+func (l *Lexer) Write(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.push.closed {
+		return 0, errWriteAfterClose
+	}
+
+	l.push.data = p
+	l.Scan(&l.push)
+	n := len(p) - len(l.push.data)
+	l.push.data = nil
+
+	if l.err != nil {
+		return n, l.err
+	}
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Close signals the end of the byte stream in push mode. A pending
+// partial token is completed and jsonlex.TokenEOF will be emitted.
+func (l *Lexer) Close() error {
+	if l.err != nil || l.push.closed {
+		return l.err
+	}
+	l.push.closed = true
+	l.Scan(&l.push)
+	return l.err
+}
+
+// chunkReader provides the chunks of push mode to Scan().
+type chunkReader struct {
+	data   []byte
+	closed bool
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		if r.closed {
+			return 0, io.EOF
+		}
+		return 0, errSuspend
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+var (
+	errSuspend         = errors.New("suspend")
+	errWriteAfterClose = errors.New("write after close")
+)
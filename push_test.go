@@ -0,0 +1,182 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// expect the same tokens for all chunk sizes
+func TestLexer_Write_1(t *testing.T) {
+	s := ` { "foo": "b\"a\\r", "baz": [ null, true, false, -42, 1.5e-3, "fälse" ] } 7`
+	e := snapshotTokens(t, s)
+
+	for size := 1; size <= len(s); size++ {
+		var toks []string
+		y := func(kind TokenKind, load []byte, pos uint) bool {
+			toks = append(toks, fmt.Sprintf("%d:%d:%s", pos, kind, load))
+			return true
+		}
+		l := NewLexer(y)
+
+		for i := 0; i < len(s); i += size {
+			j := i + size
+			if j > len(s) {
+				j = len(s)
+			}
+			if n, err := l.Write([]byte(s[i:j])); err != nil || n != j-i {
+				t.Fatalf("unexpected %d %v", n, err)
+			}
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		if fmt.Sprint(toks) != fmt.Sprint(e) {
+			t.Errorf("%d: unexpected %v", size, toks)
+		}
+	}
+}
+
+// expect early return when yield stops
+func TestLexer_Write_2(t *testing.T) {
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, string(load))
+		return !kind.Is(TokenCOM)
+	}
+	l := NewLexer(y)
+
+	p := []byte(`[1, 2]`)
+	n, err := l.Write(p)
+	if n != 3 || err != io.ErrShortWrite {
+		t.Errorf("unexpected %d %v", n, err)
+	}
+	if n, err = l.Write(p[n:]); n != 3 || err != nil {
+		t.Errorf("unexpected %d %v", n, err)
+	}
+	if err = l.Close(); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if fmt.Sprint(toks) != "[[ 1 , 2 ] ]" {
+		t.Errorf("unexpected %v", toks)
+	}
+}
+
+// expect sticky errors
+func TestLexer_Write_3(t *testing.T) {
+	i := 0
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		i++
+		return true
+	}
+	l := NewLexer(y)
+
+	if _, err := l.Write([]byte(`[1, *`)); err == nil {
+		t.Errorf("unexpected")
+	}
+	if _, err := l.Write([]byte(`]`)); err == nil {
+		t.Errorf("unexpected")
+	}
+	if err := l.Close(); err == nil {
+		t.Errorf("unexpected")
+	}
+	if i != 4 {
+		t.Errorf("unexpected %d", i)
+	}
+
+	l = NewLexer(y)
+	if err := l.Close(); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if _, err := l.Write([]byte(`1`)); err != errWriteAfterClose {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+// expect errors at the end of the stream
+func TestLexer_Write_4(t *testing.T) {
+	for _, s := range []string{`1e`, `nul`, `-`} {
+		var last TokenKind
+		l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+			last = kind
+			return true
+		})
+		if _, err := l.Write([]byte(s)); err != nil {
+			t.Errorf("unexpected %v", err)
+		}
+		if err := l.Close(); err == nil || !last.Is(TokenERR) {
+			t.Errorf("unexpected %v", err)
+		}
+	}
+}
+
+// snapshot of a partial token
+func TestLexer_Write_5(t *testing.T) {
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, fmt.Sprintf("%d:%s", pos, load))
+		return true
+	}
+
+	l := NewLexer(y)
+	_, _ = l.Write([]byte(`["a\`))
+	snap := l.Snapshot()
+	_, _ = l.Write([]byte(`"b", 1.5e`))
+	snap2 := l.Snapshot()
+
+	l = NewLexer(y)
+	if err := l.Restore(snap); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	_, _ = l.Write([]byte(`"b", 1.5e`))
+	if string(l.Snapshot()) != string(snap2) {
+		t.Errorf("unexpected")
+	}
+
+	l = NewLexer(y)
+	if err := l.Restore(snap2); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	_, _ = l.Write([]byte(`-3]`))
+	_ = l.Close()
+
+	e := `[0:[ 1:a\"b 7:, 1:a\"b 7:, 9:1.5e-3 15:] 16:]`
+	if fmt.Sprint(toks) != e {
+		t.Errorf("unexpected %v", toks)
+	}
+}
+
+// expect the same tokens and errors as Scan() for any chunking
+func TestLexer_Write_7(t *testing.T) {
+	for _, s := range []string{
+		`ft`, `f`, `1-`, `]ne`, `tru`, `[1.`, `1e+`, `-`, `"a`, `[1, nul]`, `{"a": -2.5e3}`, `true false`,
+	} {
+		var toks []string
+		y := func(kind TokenKind, load []byte, pos uint) bool {
+			toks = append(toks, fmt.Sprintf("%d:%s:%d", kind, load, pos))
+			return true
+		}
+		l := NewLexer(y)
+		l.Scan(strings.NewReader(s))
+		e := strings.Join(toks, " ")
+
+		for size := 1; size <= len(s); size++ {
+			toks = toks[:0]
+			l = NewLexer(y)
+			for i := 0; i < len(s); i += size {
+				j := i + size
+				if j > len(s) {
+					j = len(s)
+				}
+				_, _ = l.Write([]byte(s[i:j]))
+			}
+			_ = l.Close()
+			if a := strings.Join(toks, " "); a != e {
+				t.Errorf("unexpected %s for %s, expected %s", a, s, e)
+			}
+		}
+	}
+}
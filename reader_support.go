@@ -73,3 +73,127 @@ const (
 	singleByteUnreadableReaderStateBuffered
 	singleByteUnreadableReaderStateRewind
 )
+
+// MultiByteUnreadableReader is a buffering UnreadableReader which can
+// unread and peek multiple bytes and reports its position in the stream.
+// Several consumers (e.g. Lexers configured with LexerOptEnableUnreadBuffer
+// and framing layers) may take turns reading from it without losing bytes.
+// It is not safe for concurrent use.
+type MultiByteUnreadableReader struct {
+	delegate io.Reader
+	buf      []byte // history and lookahead
+	rpos     int    // read position in buf
+	high     int    // furthest read position in buf
+	size     int    // maximum history and lookahead
+	base     uint   // stream position of buf[0]
+	err      error  // pending delegate error
+}
+
+// NewMultiByteUnreadableReader wraps the given io.Reader. The returned
+// reader can unread and peek up to size bytes.
+func NewMultiByteUnreadableReader(r io.Reader, size int) *MultiByteUnreadableReader {
+	if size < 1 {
+		size = 1
+	}
+	return &MultiByteUnreadableReader{
+		delegate: r,
+		buf:      make([]byte, 0, 2*size),
+		size:     size,
+	}
+}
+
+// Read reads up to len(p) bytes into p.
+func (r *MultiByteUnreadableReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for i := 0; r.rpos == len(r.buf); i++ {
+		if r.err != nil || i == maxEmptyReads {
+			return 0, r.readErr()
+		}
+		r.fill(1)
+	}
+	n := copy(p, r.buf[r.rpos:])
+	if r.rpos += n; r.rpos > r.high {
+		r.high = r.rpos
+	}
+	return n, nil
+}
+
+// ReadByte reads a single byte.
+func (r *MultiByteUnreadableReader) ReadByte() (byte, error) {
+	var b [1]byte
+	if _, err := r.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// UnreadByte unreads the last read byte.
+func (r *MultiByteUnreadableReader) UnreadByte() error {
+	return r.UnreadBytes(1)
+}
+
+// UnreadBytes unreads the last n read bytes. It fails with io.ErrShortBuffer
+// when more than size bytes would be unread in total.
+func (r *MultiByteUnreadableReader) UnreadBytes(n int) error {
+	if n < 0 || n > r.rpos || r.high-r.rpos+n > r.size {
+		return io.ErrShortBuffer
+	}
+	r.rpos -= n
+	return nil
+}
+
+// Peek returns the next n bytes without advancing the reader. The bytes
+// are only valid until the next read operation. If Peek returns fewer
+// than n bytes, it also returns an error explaining why. It fails with
+// io.ErrShortBuffer when n exceeds the lookahead size.
+func (r *MultiByteUnreadableReader) Peek(n int) ([]byte, error) {
+	if n < 0 || n > r.size {
+		return nil, io.ErrShortBuffer
+	}
+	for i := 0; len(r.buf)-r.rpos < n && r.err == nil && i < maxEmptyReads; {
+		if r.fill(n) == 0 {
+			i++
+		}
+	}
+	if avail := len(r.buf) - r.rpos; avail < n {
+		return r.buf[r.rpos:], r.readErr()
+	}
+	return r.buf[r.rpos : r.rpos+n], nil
+}
+
+// Pos returns the position in the stream, which is the number
+// of bytes read minus the number of bytes unread.
+func (r *MultiByteUnreadableReader) Pos() uint {
+	return r.base + uint(r.rpos)
+}
+
+// fill reads from the delegate once, after making room for at least
+// n bytes of lookahead while retaining up to size bytes of history.
+func (r *MultiByteUnreadableReader) fill(n int) int {
+	if r.err != nil {
+		return 0
+	}
+	if cap(r.buf)-r.rpos < n || len(r.buf) == cap(r.buf) {
+		if drop := r.high - r.size; drop > 0 {
+			r.buf = r.buf[:copy(r.buf, r.buf[drop:])]
+			r.base += uint(drop)
+			r.rpos, r.high = r.rpos-drop, r.high-drop
+		}
+	}
+	m, err := r.delegate.Read(r.buf[len(r.buf):cap(r.buf)])
+	r.buf = r.buf[:len(r.buf)+m]
+	r.err = err
+	return m
+}
+
+// number of delegate reads without progress before giving up
+const maxEmptyReads = 100
+
+func (r *MultiByteUnreadableReader) readErr() error {
+	if r.err == nil {
+		return io.ErrNoProgress
+	}
+	return r.err
+}
@@ -6,7 +6,9 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 // bytes.Buffer stays bytes.Buffer
@@ -112,3 +114,161 @@ type uselessTestReader struct{}
 func (*uselessTestReader) Read([]byte) (int, error) {
 	panic("should never be called")
 }
+
+func TestMultiByteUnreadableReader_1(t *testing.T) {
+	delegate := iotest.OneByteReader(bytes.NewBufferString("0123456789"))
+	instance := NewMultiByteUnreadableReader(delegate, 4)
+
+	steps := []struct {
+		unread        int
+		peek          int
+		amount        int
+		expected      string
+		expectedError error
+		pos           uint
+	}{{ //0
+		peek:     3,
+		expected: `012`,
+		pos:      0,
+	}, { //1
+		amount:   2,
+		expected: `01`,
+		pos:      2,
+	}, { //2
+		unread: 2,
+		pos:    0,
+	}, { //3
+		amount:   3,
+		expected: `012`,
+		pos:      3,
+	}, { //4
+		amount:   4,
+		expected: `3456`,
+		pos:      7,
+	}, { //5
+		unread: 4,
+		pos:    3,
+	}, { //6
+		unread:        1,
+		expectedError: io.ErrShortBuffer,
+		pos:           3,
+	}, { //7
+		peek:          5,
+		expectedError: io.ErrShortBuffer,
+		pos:           3,
+	}, { //8
+		peek:     4,
+		expected: `3456`,
+		pos:      3,
+	}, { //9
+		amount:   4,
+		expected: `3456`,
+		pos:      7,
+	}, { //10
+		peek:          4,
+		expected:      `789`,
+		expectedError: io.EOF,
+		pos:           7,
+	}, { //11
+		amount:   3,
+		expected: `789`,
+		pos:      10,
+	}, { //12
+		amount:        1,
+		expectedError: io.EOF,
+		pos:           10,
+	}}
+
+	for i, step := range steps {
+		switch {
+		case step.unread > 0:
+			if err := instance.UnreadBytes(step.unread); err != step.expectedError {
+				t.Errorf("%d: instance.UnreadBytes() = %v, want: %v", i, err, step.expectedError)
+			}
+		case step.peek > 0:
+			actual, err := instance.Peek(step.peek)
+			if err != step.expectedError {
+				t.Errorf("%d: instance.Peek() err = %v, want: %v", i, err, step.expectedError)
+			}
+			if string(actual) != step.expected {
+				t.Errorf("%d: actual = %q, want: %q", i, actual, step.expected)
+			}
+		default:
+			actual := make([]byte, step.amount)
+			n, err := io.ReadFull(instance, actual)
+			if err != step.expectedError {
+				t.Errorf("%d: io.ReadFull() err = %v, want: %v", i, err, step.expectedError)
+			}
+			if string(actual[:n]) != step.expected {
+				t.Errorf("%d: actual = %q, want: %q", i, actual[:n], step.expected)
+			}
+		}
+		if instance.Pos() != step.pos {
+			t.Errorf("%d: instance.Pos() = %d, want: %d", i, instance.Pos(), step.pos)
+		}
+	}
+}
+
+// Lexers and a framing layer take turns on the same stream
+func TestMultiByteUnreadableReader_2(t *testing.T) {
+	r := NewMultiByteUnreadableReader(bytes.NewBufferString("42\n[1]\ntrue"), 16)
+
+	var load []string
+	y := func(kind TokenKind, l []byte, pos uint) bool {
+		load = append(load, string(l))
+		return false
+	}
+
+	NewLexer(y, LexerOptEnableUnreadBuffer).Scan(r)
+	if b, err := r.ReadByte(); err != nil || b != '\n' || r.Pos() != 3 {
+		t.Errorf("unexpected %q %v", b, err)
+	}
+
+	l := NewLexer(y, LexerOptEnableUnreadBuffer)
+	for i := 0; i < 3; i++ {
+		l.Scan(r)
+	}
+	if b, err := r.Peek(1); err != nil || b[0] != '\n' || r.Pos() != 6 {
+		t.Errorf("unexpected %q %v", b, err)
+	}
+
+	if _, err := r.ReadByte(); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	NewLexer(y, LexerOptEnableUnreadBuffer).Scan(r)
+
+	if v := strings.Join(load, " "); v != "42 [ 1 ] true" {
+		t.Errorf("unexpected %q", v)
+	}
+}
+
+func TestMultiByteUnreadableReader_3(t *testing.T) {
+	r := NewMultiByteUnreadableReader(&FaultyReader{}, 0)
+
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected %v", err)
+	}
+	if n, err := r.Read(nil); n != 0 || err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if _, err := r.ReadByte(); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected %v", err)
+	}
+	if err := r.UnreadByte(); err != io.ErrShortBuffer {
+		t.Errorf("unexpected %v", err)
+	}
+
+	r = NewMultiByteUnreadableReader(&emptyTestReader{}, 1)
+	if _, err := r.Read(make([]byte, 1)); err != io.ErrNoProgress {
+		t.Errorf("unexpected %v", err)
+	}
+	if _, err := r.Peek(1); err != io.ErrNoProgress {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+type emptyTestReader struct{}
+
+func (*emptyTestReader) Read([]byte) (int, error) {
+	return 0, nil
+}
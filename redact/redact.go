@@ -0,0 +1,242 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package redact replaces the values of sensitive object members in JSON
+// text, e.g. log lines, while streaming. Based on the token positions of
+// the jsonlex.Lexer, everything but the redacted values is copied byte
+// for byte, including whitespace.
+package redact
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/pointer"
+)
+
+type (
+	// Redactor replaces the values of members matching by name,
+	// name pattern or path.
+	Redactor struct {
+		names    map[string]bool // lower case member names
+		patterns []*regexp.Regexp
+		paths    [][]string
+		repl     []byte
+	}
+
+	frame struct {
+		obj bool
+		key string
+		idx int
+	}
+
+	// recorder keeps the bytes read by the Lexer.
+	recorder struct {
+		r    io.Reader
+		buf  []byte
+		base uint // stream position of buf[0]
+	}
+)
+
+// DefaultNames are the member names redacted by default.
+var DefaultNames = []string{"password", "token", "ssn"}
+
+type redactOpt func(*Redactor)
+
+// OptNames sets the member names whose values are redacted, replacing
+// DefaultNames. Names are compared case-insensitively.
+func OptNames(names ...string) redactOpt {
+	return func(r *Redactor) {
+		r.names = make(map[string]bool, len(names))
+		for _, n := range names {
+			r.names[strings.ToLower(n)] = true
+		}
+	}
+}
+
+// OptPatterns adds regular expressions matching
+// member names whose values are redacted.
+func OptPatterns(patterns ...*regexp.Regexp) redactOpt {
+	return func(r *Redactor) {
+		r.patterns = append(r.patterns, patterns...)
+	}
+}
+
+// OptPaths adds JSON Pointers to values to be redacted. A segment
+// of "*" matches any member name or array index.
+func OptPaths(pointers ...string) redactOpt {
+	return func(r *Redactor) {
+		for _, p := range pointers {
+			r.paths = append(r.paths, pointer.Split(p))
+		}
+	}
+}
+
+// OptReplacement sets the JSON text replacing redacted
+// values, by default it is "[REDACTED]" (a string).
+func OptReplacement(json []byte) redactOpt {
+	return func(r *Redactor) {
+		r.repl = json
+	}
+}
+
+// New creates a Redactor.
+func New(opts ...redactOpt) *Redactor {
+	r := &Redactor{repl: []byte(`"[REDACTED]"`)}
+	OptNames(DefaultNames...)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Redact copies the JSON text from src to dst and replaces the
+// matching values. A stream of concatenated top level values is
+// accepted. On malformed input, an error is returned after copying the
+// text read so far, unless it is part of a value to be redacted.
+func (r *Redactor) Redact(dst io.Writer, src io.Reader) error {
+	var (
+		rec   = &recorder{r: bufio.NewReader(src)}
+		done  uint // stream position up to which the input is handled
+		stack []frame
+		key   bool // member name matched
+		skip  int  // depth of redacted container, 0 if none
+		err   error
+	)
+
+	// flush writes the input up to the position.
+	flush := func(pos uint) {
+		if err == nil && pos > done {
+			_, err = dst.Write(rec.buf[done-rec.base : pos-rec.base])
+		}
+		done = pos
+		rec.discard(done)
+	}
+	replace := func(end uint) {
+		if err == nil {
+			_, err = dst.Write(r.repl)
+		}
+		done = end
+		rec.discard(done)
+	}
+
+	l := jsonlex.NewLexer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		switch kind {
+		case jsonlex.TokenEOF, jsonlex.TokenERR:
+			return false
+		case jsonlex.TokenCOL, jsonlex.TokenCOM:
+			return true
+		case jsonlex.TokenRCB, jsonlex.TokenRSB:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			key = false
+			if skip > 0 && len(stack) == skip-1 {
+				skip = 0
+				replace(pos + 1)
+			}
+			return err == nil
+		}
+		if skip > 0 {
+			if kind == jsonlex.TokenLCB || kind == jsonlex.TokenLSB {
+				stack = append(stack, frame{})
+			}
+			done = pos // the redacted bytes are not written
+			rec.discard(done)
+			return true
+		}
+
+		if kind == jsonlex.TokenKEY {
+			name := string(load)
+			if bytes.IndexByte(load, '\\') >= 0 {
+				u, _ := jsonlex.Unescape(nil, load)
+				name = string(u)
+			}
+			stack[len(stack)-1].key = name
+			key = r.matchName(name)
+			return true
+		}
+
+		// first token of a value
+		if n := len(stack); n > 0 && !stack[n-1].obj {
+			stack[n-1].idx++
+		}
+		match := key || r.matchPath(stack)
+		key = false
+
+		flush(pos)
+		switch {
+		case kind == jsonlex.TokenLCB || kind == jsonlex.TokenLSB:
+			stack = append(stack, frame{obj: kind == jsonlex.TokenLCB, idx: -1})
+			if match {
+				skip = len(stack)
+			}
+		case match && kind == jsonlex.TokenSTR:
+			replace(pos + uint(len(load)) + 2)
+		case match:
+			replace(pos + uint(len(load)))
+		}
+		return err == nil
+	}, jsonlex.LexerOptEmitKeys)
+
+	l.Scan(rec)
+	if err != nil {
+		return err
+	}
+	if skip == 0 {
+		flush(rec.base + uint(len(rec.buf)))
+	}
+	if err != nil {
+		return err
+	}
+	return l.Err()
+}
+
+func (r *Redactor) matchName(name string) bool {
+	if r.names[strings.ToLower(name)] {
+		return true
+	}
+	for _, p := range r.patterns {
+		if p.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) matchPath(stack []frame) bool {
+next:
+	for _, p := range r.paths {
+		if len(p) != len(stack) {
+			continue
+		}
+		for i, s := range p {
+			if s == "*" {
+				continue
+			}
+			if f := stack[i]; f.obj && s != f.key || !f.obj && s != strconv.Itoa(f.idx) {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (rec *recorder) Read(p []byte) (int, error) {
+	n, err := rec.r.Read(p)
+	rec.buf = append(rec.buf, p[:n]...)
+	return n, err
+}
+
+// discard drops the recorded bytes before the position.
+func (rec *recorder) discard(pos uint) {
+	if n := pos - rec.base; n > 4096 && n > uint(len(rec.buf))/2 {
+		rec.buf = rec.buf[:copy(rec.buf, rec.buf[n:])]
+		rec.base = pos
+	}
+}
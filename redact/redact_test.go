@@ -0,0 +1,91 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package redact
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func redact(t *testing.T, s string, opts ...redactOpt) string {
+	buf := bytes.Buffer{}
+	if err := New(opts...).Redact(&buf, strings.NewReader(s)); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	return buf.String()
+}
+
+func TestRedactor_Redact_1(t *testing.T) {
+	s := "{ \"user\":\"a\",  \"Password\" : \"s3cr\\\"et\" , \"n\":1.50 }\n" +
+		"{\"token\": {\"a\": [1, {}]}, \"list\": [ {\"ssn\": 123}, true ] ,\"x\" :null}\n"
+	e := "{ \"user\":\"a\",  \"Password\" : \"[REDACTED]\" , \"n\":1.50 }\n" +
+		"{\"token\": \"[REDACTED]\", \"list\": [ {\"ssn\": \"[REDACTED]\"}, true ] ,\"x\" :null}\n"
+
+	if r := redact(t, s); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestRedactor_Redact_2(t *testing.T) {
+	s := `{"api_key": "k", "password": "p", "a": [{"b": 1, "c": 2}, {"b": 3}], "d\/e": {"f": null}} 7`
+	e := `{"api_key": null, "password": "p", "a": [{"b": null, "c": 2}, {"b": null}], "d\/e": {"f": null}} 7`
+
+	opts := []redactOpt{
+		OptNames("none"),
+		OptPatterns(regexp.MustCompile(`(?i)_key$`)),
+		OptPaths("/a/*/b", "/d~1e/f"),
+		OptReplacement([]byte("null")),
+	}
+	if r := redact(t, s, opts...); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+	if r := redact(t, `{"a": 1} "b"`, OptPaths("")); r != `"[REDACTED]" "[REDACTED]"` {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+// large input, the recorded bytes are discarded on the way
+func TestRedactor_Redact_3(t *testing.T) {
+	line := `{"msg": "` + strings.Repeat("x", 1000) + `", "token": "abc", "n": [1, 2, 3]}` + "\n"
+	s := strings.Repeat(line, 100)
+
+	r := redact(t, s)
+	if r != strings.Replace(s, `"abc"`, `"[REDACTED]"`, -1) {
+		t.Errorf("unexpected")
+	}
+}
+
+func TestRedactor_Redact_4(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := New().Redact(&buf, strings.NewReader(`{"a": 1, "token": [1, @`))
+
+	if err == nil || buf.String() != `{"a": 1, "token": ` {
+		t.Errorf("unexpected %v %s", err, buf.String())
+	}
+}
+
+func TestRedactor_Redact_5(t *testing.T) {
+	s := `[{"password": }, "x", {"a": {"token": ]}, "y"}]`
+	if r := redact(t, s); r != s {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestRedactor_Redact_6(t *testing.T) {
+	s := `{"password": [` + strings.Repeat(`"abcdefgh",`, 1<<17) + `0], "a": 1}`
+
+	var m0, m1 runtime.MemStats
+	runtime.ReadMemStats(&m0)
+	if err := New().Redact(ioutil.Discard, strings.NewReader(s)); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	runtime.ReadMemStats(&m1)
+
+	if n := m1.TotalAlloc - m0.TotalAlloc; n > uint64(len(s)/4) {
+		t.Errorf("unexpected %d bytes allocated for %d bytes", n, len(s))
+	}
+}
@@ -0,0 +1,307 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package schema validates JSON documents against a JSON Schema while
+// streaming the tokens of the jsonlex.Lexer, the document is never held
+// in memory. Supported are the keywords type, properties, required,
+// additionalProperties, items (schema and tuple form), enum, const,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength,
+// maxLength, pattern, minItems, maxItems, minProperties and maxProperties.
+// Other keywords are ignored. Patterns use the syntax of package regexp.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dtgorski/jsonlex/dom"
+	"github.com/dtgorski/jsonlex/internal/number"
+	"github.com/dtgorski/jsonlex/internal/pointer"
+)
+
+type (
+	// Schema is a compiled JSON Schema.
+	Schema struct {
+		root *node
+	}
+
+	node struct {
+		never     bool // false schema
+		types     typeSet
+		props     map[string]*node
+		addl      *node // additionalProperties
+		required  []string
+		items     *node
+		tuple     []*node
+		enum      []string // canonical values
+		konst     *string  // canonical value
+		minimum   *float64
+		maximum   *float64
+		exclMin   *float64
+		exclMax   *float64
+		minLength int
+		maxLength int
+		minItems  int
+		maxItems  int
+		minProps  int
+		maxProps  int
+		pattern   *regexp.Regexp
+	}
+
+	typeSet uint8
+)
+
+// Types of values.
+const (
+	typeNull typeSet = 1 << iota
+	typeBoolean
+	typeObject
+	typeArray
+	typeNumber
+	typeInteger
+	typeString
+)
+
+var typeNames = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Compile parses and compiles a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	v, err := dom.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root, err := compile(v, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+func compile(v dom.Value, ptr string) (*node, error) {
+	n := &node{minLength: -1, maxLength: -1, minItems: -1, maxItems: -1, minProps: -1, maxProps: -1}
+
+	switch v.Kind() {
+	case dom.Bool:
+		n.never = !v.Bool()
+		return n, nil
+	case dom.Object:
+	default:
+		return nil, fmt.Errorf("invalid schema at %q", ptr)
+	}
+
+	var err error
+	v.ForEach(func(_ int, m dom.Value) bool {
+		err = n.keyword(m.Key(), m, ptr+"/"+pointer.Escape(m.Key()))
+		return err == nil
+	})
+	return n, err
+}
+
+func (n *node) keyword(kw string, v dom.Value, ptr string) error {
+	invalid := fmt.Errorf("invalid keyword %q at %q", kw, ptr)
+
+	switch kw {
+	case "type":
+		if v.Kind() == dom.String {
+			v = wrap(v)
+		}
+		if v.Kind() != dom.Array {
+			return invalid
+		}
+		ok := true
+		v.ForEach(func(_ int, e dom.Value) bool {
+			t := lookupType(e.String())
+			ok = e.Kind() == dom.String && t != 0
+			n.types |= t
+			return ok
+		})
+		if !ok {
+			return invalid
+		}
+
+	case "properties":
+		if v.Kind() != dom.Object {
+			return invalid
+		}
+		n.props = make(map[string]*node, v.Len())
+		var err error
+		v.ForEach(func(_ int, m dom.Value) bool {
+			n.props[m.Key()], err = compile(m, ptr+"/"+pointer.Escape(m.Key()))
+			return err == nil
+		})
+		return err
+
+	case "additionalProperties":
+		s, err := compile(v, ptr)
+		n.addl = s
+		return err
+
+	case "required":
+		if v.Kind() != dom.Array {
+			return invalid
+		}
+		ok := true
+		v.ForEach(func(_ int, e dom.Value) bool {
+			ok = e.Kind() == dom.String
+			n.required = append(n.required, e.String())
+			return ok
+		})
+		if !ok {
+			return invalid
+		}
+
+	case "items":
+		if v.Kind() != dom.Array {
+			s, err := compile(v, ptr)
+			n.items = s
+			return err
+		}
+		var err error
+		v.ForEach(func(i int, e dom.Value) bool {
+			var s *node
+			s, err = compile(e, ptr+"/"+strconv.Itoa(i))
+			n.tuple = append(n.tuple, s)
+			return err == nil
+		})
+		return err
+
+	case "enum":
+		if v.Kind() != dom.Array {
+			return invalid
+		}
+		v.ForEach(func(_ int, e dom.Value) bool {
+			n.enum = append(n.enum, canonical(e))
+			return true
+		})
+
+	case "const":
+		c := canonical(v)
+		n.konst = &c
+
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+		f, err := v.Float()
+		if err != nil {
+			return invalid
+		}
+		switch kw {
+		case "minimum":
+			n.minimum = &f
+		case "maximum":
+			n.maximum = &f
+		case "exclusiveMinimum":
+			n.exclMin = &f
+		case "exclusiveMaximum":
+			n.exclMax = &f
+		}
+
+	case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+		i, err := v.Int()
+		if err != nil || i < 0 || i > math.MaxInt32 {
+			return invalid
+		}
+		switch kw {
+		case "minLength":
+			n.minLength = int(i)
+		case "maxLength":
+			n.maxLength = int(i)
+		case "minItems":
+			n.minItems = int(i)
+		case "maxItems":
+			n.maxItems = int(i)
+		case "minProperties":
+			n.minProps = int(i)
+		case "maxProperties":
+			n.maxProps = int(i)
+		}
+
+	case "pattern":
+		if v.Kind() != dom.String {
+			return invalid
+		}
+		re, err := regexp.Compile(v.String())
+		if err != nil {
+			return fmt.Errorf("invalid keyword %q at %q: %s", kw, ptr, err)
+		}
+		n.pattern = re
+	}
+	return nil
+}
+
+// canonical returns a representation of the value for comparison:
+// numbers are normalized, strings unescaped, object members sorted.
+func canonical(v dom.Value) string {
+	b := strings.Builder{}
+	writeCanonical(&b, v)
+	return b.String()
+}
+
+func writeCanonical(b *strings.Builder, v dom.Value) {
+	switch v.Kind() {
+	case dom.Number:
+		b.WriteString(number.Normal(v.Raw()))
+	case dom.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case dom.Array:
+		b.WriteByte('[')
+		v.ForEach(func(i int, e dom.Value) bool {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonical(b, e)
+			return true
+		})
+		b.WriteByte(']')
+	case dom.Object:
+		members := map[string]dom.Value{}
+		v.ForEach(func(_ int, m dom.Value) bool {
+			members[m.Key()] = m // last wins
+			return true
+		})
+		keys := make([]string, 0, len(members))
+		for k := range members {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(k))
+			b.WriteByte(':')
+			writeCanonical(b, members[k])
+		}
+		b.WriteByte('}')
+	default:
+		b.Write(v.Raw())
+	}
+}
+
+// wrap turns a single value into an array of one element.
+func wrap(v dom.Value) dom.Value {
+	w, _ := dom.Parse(append(append([]byte{'['}, v.Raw()...), ']'))
+	return w
+}
+
+func lookupType(name string) typeSet {
+	for i, n := range typeNames {
+		if n == name {
+			return 1 << uint(i)
+		}
+	}
+	return 0
+}
+
+func (t typeSet) String() string {
+	var names []string
+	for i, n := range typeNames {
+		if t&(1<<uint(i)) != 0 {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, " or ")
+}
@@ -0,0 +1,55 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/dtgorski/jsonlex/dom"
+)
+
+func TestCompile_1(t *testing.T) {
+	for _, s := range []string{
+		`true`, `false`, `{}`,
+		`{"type": "string", "minLength": 1, "pattern": "^a"}`,
+		`{"type": ["object", "null"], "properties": {"a": {"enum": [1, "x", {"b": [null]}]}}}`,
+		`{"items": [{"const": 1}, true], "additionalProperties": false, "required": ["a"]}`,
+		`{"unknown": 42, "minimum": -1.5, "exclusiveMaximum": 1e3}`,
+	} {
+		if _, err := Compile([]byte(s)); err != nil {
+			t.Errorf("unexpected %s: %s", s, err)
+		}
+	}
+}
+
+func TestCompile_2(t *testing.T) {
+	for _, s := range []string{
+		``, `1`, `"x"`, `{"type": "foo"}`, `{"type": 1}`, `{"properties": []}`,
+		`{"required": [1]}`, `{"minimum": "1"}`, `{"minLength": -1}`, `{"maxItems": 1.5}`,
+		`{"pattern": "("}`, `{"properties": {"a": 1}}`, `{"items": [1]}`, `{"enum": {}}`,
+	} {
+		if _, err := Compile([]byte(s)); err == nil {
+			t.Errorf("unexpected nil error: %s", s)
+		}
+	}
+}
+
+func TestCanonical_1(t *testing.T) {
+	for s, e := range map[string]string{
+		`1.0`:                       `1`,
+		`-2.5e1`:                    `-25`,
+		`1.50`:                      `15e-1`,
+		`-0.0e5`:                    `0`,
+		`12E+30`:                    `12e30`,
+		`9007199254740993`:          `9007199254740993`,
+		`"ab"`:                      `"ab"`,
+		`{"b": 1, "a": [true, {}]}`: `{"a":[true,{}],"b":1}`,
+		`{"a": 1, "a": 2}`:          `{"a":2}`,
+		`null`:                      `null`,
+	} {
+		v, _ := dom.Parse([]byte(s))
+		if c := canonical(v); c != e {
+			t.Errorf("unexpected %s", c)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/dom"
+	"github.com/dtgorski/jsonlex/internal/number"
+	"github.com/dtgorski/jsonlex/internal/pointer"
+)
+
+type (
+	// Validator checks a token stream against a Schema. Its Yield method
+	// is meant to be handed over to jsonlex.NewLexer(). A stream of
+	// concatenated top level values is accepted, each value is checked.
+	Validator struct {
+		root  *node
+		g     jsonlex.Grammar
+		stack []frame
+		viols []Violation
+		err   error
+		capts int // number of capturing frames
+	}
+
+	// Violation describes a value which fails a constraint of the Schema.
+	Violation struct {
+		Pointer string // JSON Pointer (RFC 6901) to the value
+		Pos     uint   // byte position of the value
+		Msg     string // description of the failed constraint
+	}
+
+	frame struct {
+		node    *node             // schema of the container, nil if unconstrained
+		kind    jsonlex.TokenKind // jsonlex.TokenLCB or jsonlex.TokenLSB
+		pos     uint
+		count   int             // members or elements
+		key     string          // current member name
+		seen    map[string]bool // member names, for required
+		capt    []byte          // JSON text, for enum and const
+		capture bool
+	}
+)
+
+// NewValidator creates a Validator for a single token stream.
+func (s *Schema) NewValidator() *Validator {
+	return &Validator{root: s.root}
+}
+
+// Validate reads the document from r and checks it against the Schema.
+// Malformed input results in an error.
+func (s *Schema) Validate(r io.Reader) ([]Violation, error) {
+	v := s.NewValidator()
+	l := jsonlex.NewLexer(v.Yield)
+	l.Scan(r)
+
+	if err := l.Err(); err != nil {
+		return v.viols, err
+	}
+	return v.viols, v.err
+}
+
+// Violations returns the violations found so far.
+func (v *Validator) Violations() []Violation {
+	return v.viols
+}
+
+// Err returns the error which stopped the Validator, if any.
+func (v *Validator) Err() error {
+	return v.err
+}
+
+// Yield consumes a token, it is a jsonlex.Yield function. Refined
+// token kinds, see jsonlex.LexerOptEmitKeys, are accepted.
+func (v *Validator) Yield(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+	if v.err != nil {
+		return false
+	}
+	switch kind {
+	case jsonlex.TokenERR:
+		v.err = errors.New(string(load))
+		return false
+	case jsonlex.TokenEOF:
+		if !v.g.Done() {
+			v.err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: pos}
+		}
+		return false
+	}
+
+	key, ok := v.g.Push(kind)
+	if !ok {
+		v.unexpected(load, pos)
+		return false
+	}
+	switch kind {
+	case jsonlex.TokenKEY:
+		kind = jsonlex.TokenSTR
+	case jsonlex.TokenINT, jsonlex.TokenFLT:
+		kind = jsonlex.TokenNUM
+	}
+
+	if v.capts > 0 {
+		v.capture(kind, load)
+	}
+
+	switch {
+	case key:
+		v.key(&v.stack[len(v.stack)-1], load, pos)
+	case kind == jsonlex.TokenCOL, kind == jsonlex.TokenCOM:
+	case kind == jsonlex.TokenRCB, kind == jsonlex.TokenRSB:
+		v.close()
+	case len(v.stack) == 0:
+		v.value(v.root, kind, load, pos)
+	default:
+		f := &v.stack[len(v.stack)-1]
+		f.count++
+		v.value(v.child(f), kind, load, pos)
+	}
+	return v.err == nil
+}
+
+// key handles an object member name.
+func (v *Validator) key(f *frame, load []byte, pos uint) {
+	f.key = unescape(load)
+
+	if f.seen != nil {
+		f.seen[f.key] = true
+	}
+	if n := f.node; n != nil && n.addl != nil && n.addl.never {
+		if _, ok := n.props[f.key]; !ok {
+			v.violation(v.pointer(len(v.stack)+1), pos, "additional property %q not allowed", f.key)
+		}
+	}
+}
+
+// child returns the schema of the current child of the container.
+func (v *Validator) child(f *frame) *node {
+	n := f.node
+	if n == nil {
+		return nil
+	}
+	if f.kind == jsonlex.TokenLSB {
+		if i := f.count - 1; i < len(n.tuple) {
+			return n.tuple[i]
+		}
+		return n.items
+	}
+	if p, ok := n.props[f.key]; ok {
+		return p
+	}
+	if n.addl != nil && n.addl.never {
+		return nil // reported with the member name
+	}
+	return n.addl
+}
+
+// value handles the first token of a value.
+func (v *Validator) value(n *node, kind jsonlex.TokenKind, load []byte, pos uint) {
+	var t typeSet
+	switch kind {
+	case jsonlex.TokenLCB:
+		t = typeObject
+	case jsonlex.TokenLSB:
+		t = typeArray
+	case jsonlex.TokenSTR:
+		t = typeString
+	case jsonlex.TokenNUM:
+		t = typeNumber
+	case jsonlex.TokenLIT:
+		if t = typeBoolean; load[0] == 'n' {
+			t = typeNull
+		}
+	}
+
+	if t == typeObject || t == typeArray {
+		f := frame{node: n, kind: kind, pos: pos}
+		if n != nil && len(n.required) > 0 {
+			f.seen = make(map[string]bool)
+		}
+		if n != nil && (n.enum != nil || n.konst != nil) {
+			f.capture, f.capt = true, append(f.capt, load...)
+			v.capts++
+		}
+		if n != nil {
+			v.check(n, t, nil, pos, v.pointer(len(v.stack)+1))
+		}
+		v.stack = append(v.stack, f)
+		return
+	}
+
+	if n != nil {
+		v.check(n, t, load, pos, v.pointer(len(v.stack)+1))
+	}
+}
+
+// close handles the end of a container.
+func (v *Validator) close() {
+	f := v.stack[len(v.stack)-1]
+	ptr := v.pointer(len(v.stack))
+	v.stack = v.stack[:len(v.stack)-1]
+
+	n := f.node
+	if n == nil {
+		return
+	}
+	if f.kind == jsonlex.TokenLCB {
+		for _, r := range n.required {
+			if !f.seen[r] {
+				v.violation(ptr, f.pos, "missing required property %q", r)
+			}
+		}
+		if n.minProps >= 0 && f.count < n.minProps {
+			v.violation(ptr, f.pos, "fewer than %d properties", n.minProps)
+		}
+		if n.maxProps >= 0 && f.count > n.maxProps {
+			v.violation(ptr, f.pos, "more than %d properties", n.maxProps)
+		}
+	} else {
+		if n.minItems >= 0 && f.count < n.minItems {
+			v.violation(ptr, f.pos, "fewer than %d items", n.minItems)
+		}
+		if n.maxItems >= 0 && f.count > n.maxItems {
+			v.violation(ptr, f.pos, "more than %d items", n.maxItems)
+		}
+	}
+	if f.capture {
+		v.capts--
+		if d, err := dom.Parse(f.capt); err == nil {
+			v.compare(n, canonical(d), ptr, f.pos)
+		}
+	}
+}
+
+// check applies the constraints of the schema to a value.
+// The load of containers is nil, they are checked by close().
+func (v *Validator) check(n *node, t typeSet, load []byte, pos uint, ptr string) {
+	if n.never {
+		v.violation(ptr, pos, "value not allowed")
+		return
+	}
+
+	var num float64
+	if t == typeNumber {
+		num, _ = strconv.ParseFloat(string(load), 64)
+		if num == math.Trunc(num) && !math.IsInf(num, 0) {
+			t |= typeInteger
+		}
+	}
+	if n.types != 0 && n.types&t == 0 {
+		got := t
+		if got&typeInteger != 0 {
+			got = typeInteger
+		}
+		v.violation(ptr, pos, "expected %s, got %s", n.types, got)
+	}
+
+	switch {
+	case t&typeNumber != 0:
+		if n.minimum != nil && num < *n.minimum {
+			v.violation(ptr, pos, "less than minimum %g", *n.minimum)
+		}
+		if n.maximum != nil && num > *n.maximum {
+			v.violation(ptr, pos, "greater than maximum %g", *n.maximum)
+		}
+		if n.exclMin != nil && num <= *n.exclMin {
+			v.violation(ptr, pos, "not greater than exclusive minimum %g", *n.exclMin)
+		}
+		if n.exclMax != nil && num >= *n.exclMax {
+			v.violation(ptr, pos, "not less than exclusive maximum %g", *n.exclMax)
+		}
+		if n.enum != nil || n.konst != nil {
+			v.compare(n, number.Normal(load), ptr, pos)
+		}
+
+	case t == typeString:
+		if n.minLength < 0 && n.maxLength < 0 && n.pattern == nil && n.enum == nil && n.konst == nil {
+			return
+		}
+		s := unescape(load)
+		if l := utf8.RuneCountInString(s); n.minLength >= 0 && l < n.minLength {
+			v.violation(ptr, pos, "shorter than %d characters", n.minLength)
+		} else if n.maxLength >= 0 && l > n.maxLength {
+			v.violation(ptr, pos, "longer than %d characters", n.maxLength)
+		}
+		if n.pattern != nil && !n.pattern.MatchString(s) {
+			v.violation(ptr, pos, "does not match pattern %q", n.pattern)
+		}
+		if n.enum != nil || n.konst != nil {
+			v.compare(n, strconv.Quote(s), ptr, pos)
+		}
+
+	case t == typeNull || t == typeBoolean:
+		if n.enum != nil || n.konst != nil {
+			v.compare(n, string(load), ptr, pos)
+		}
+	}
+}
+
+// compare checks the canonical representation of a value against enum and const.
+func (v *Validator) compare(n *node, c string, ptr string, pos uint) {
+	if n.konst != nil && c != *n.konst {
+		v.violation(ptr, pos, "not equal to const value")
+	}
+	if n.enum == nil {
+		return
+	}
+	for _, e := range n.enum {
+		if c == e {
+			return
+		}
+	}
+	v.violation(ptr, pos, "not one of the enum values")
+}
+
+// capture appends the token to the JSON text of the capturing frames.
+func (v *Validator) capture(kind jsonlex.TokenKind, load []byte) {
+	for i := range v.stack {
+		if f := &v.stack[i]; f.capture {
+			if kind == jsonlex.TokenSTR {
+				f.capt = append(append(append(f.capt, '"'), load...), '"')
+			} else {
+				f.capt = append(f.capt, load...)
+			}
+		}
+	}
+}
+
+// pointer returns the JSON Pointer of the current
+// child of the frame at the given depth.
+func (v *Validator) pointer(depth int) string {
+	if depth <= 1 {
+		return ""
+	}
+	b := strings.Builder{}
+	for _, f := range v.stack[:depth-1] {
+		b.WriteByte('/')
+		if f.kind == jsonlex.TokenLCB {
+			b.WriteString(pointer.Escape(f.key))
+		} else {
+			b.WriteString(strconv.Itoa(f.count - 1))
+		}
+	}
+	return b.String()
+}
+
+func (v *Validator) violation(ptr string, pos uint, format string, args ...interface{}) {
+	v.viols = append(v.viols, Violation{Pointer: ptr, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *Validator) unexpected(load []byte, pos uint) {
+	v.err = &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+}
+
+func unescape(load []byte) string {
+	if bytes.IndexByte(load, '\\') < 0 {
+		return string(load)
+	}
+	s, err := jsonlex.Unescape(nil, load)
+	if err != nil {
+		return string(load)
+	}
+	return string(s)
+}
@@ -0,0 +1,116 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["id", "name", "tags"],
+	"additionalProperties": false,
+	"properties": {
+		"id":    {"type": "integer", "minimum": 1},
+		"name":  {"type": "string", "minLength": 2, "maxLength": 5, "pattern": "^[a-z]+$"},
+		"score": {"type": "number", "exclusiveMinimum": 0, "maximum": 10},
+		"kind":  {"enum": ["a", "b", {"x": [1, 2]}]},
+		"tags":  {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 2},
+		"pair":  {"items": [{"type": "null"}, {"const": {"k": true}}]},
+		"a/b":   false
+	}
+}`
+
+func validate(t *testing.T, doc string) []string {
+	s, err := Compile([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	viols, err := s.Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	var r []string
+	for _, v := range viols {
+		r = append(r, fmt.Sprintf("%s@%d: %s", v.Pointer, v.Pos, v.Msg))
+	}
+	return r
+}
+
+func TestSchema_Validate_1(t *testing.T) {
+	doc := `{"id": 7, "name": "abc", "score": 10, "kind": {"x": [1.0, 2]}, "tags": ["t"], "pair": [null, {"k": true}]}`
+	if v := validate(t, doc); len(v) != 0 {
+		t.Errorf("unexpected %v", v)
+	}
+}
+
+func TestSchema_Validate_2(t *testing.T) {
+	doc := `{"id": 1.5, "name": "A", "score": 0, "kind": "c", "tags": [1, "u", "v"], "x": 1, "a/b": 2, "pair": [0, {"k": false}]}`
+	e := []string{
+		"/id@7: expected integer, got number",
+		"/name@20: shorter than 2 characters",
+		`/name@20: does not match pattern "^[a-z]+$"`,
+		"/score@34: not greater than exclusive minimum 0",
+		"/kind@45: not one of the enum values",
+		"/tags/0@59: expected string, got integer",
+		"/tags@58: more than 2 items",
+		`/x@73: additional property "x" not allowed`,
+		"/a~1b@88: value not allowed",
+		"/pair/0@100: expected null, got integer",
+		"/pair/1@103: not equal to const value",
+	}
+	if v := validate(t, doc); strings.Join(v, "\n") != strings.Join(e, "\n") {
+		t.Errorf("unexpected\n%s", strings.Join(v, "\n"))
+	}
+}
+
+func TestSchema_Validate_3(t *testing.T) {
+	e := []string{
+		`@0: missing required property "id"`,
+		`@0: missing required property "name"`,
+		`@0: missing required property "tags"`,
+		"@3: expected object, got array",
+	}
+	if v := validate(t, `{} []`); strings.Join(v, "\n") != strings.Join(e, "\n") {
+		t.Errorf("unexpected\n%s", strings.Join(v, "\n"))
+	}
+}
+
+func TestSchema_Validate_4(t *testing.T) {
+	s, _ := Compile([]byte(`{"items": {"type": "string"}}`))
+
+	for _, doc := range []string{
+		`[1,]`, `[1 2]`, `{"a" 1}`, `[}`, `["a"`, `]`, `{"a":}`, `@`,
+		`{1:2}`, `{"a" "b"}`, `{"a":1,,"b":2}`, `[,]`, `{"a":1}}`,
+	} {
+		if _, err := s.Validate(strings.NewReader(doc)); err == nil {
+			t.Errorf("unexpected nil error: %s", doc)
+		}
+	}
+}
+
+func TestValidator_Yield_1(t *testing.T) {
+	s, _ := Compile([]byte(`{"properties": {"n": {"maxLength": 1}}}`))
+	v := s.NewValidator()
+
+	l := jsonlex.NewLexer(v.Yield, jsonlex.LexerOptEmitKeys, jsonlex.LexerOptEmitNumberKinds)
+	l.Scan(bytes.NewReader([]byte(`{"n": "äö", "m": 1}`)))
+
+	if v.Err() != nil || len(v.Violations()) != 1 || v.Violations()[0].Pointer != "/n" {
+		t.Errorf("unexpected %v %v", v.Err(), v.Violations())
+	}
+}
+
+func TestSchema_Validate_5(t *testing.T) {
+	s, _ := Compile([]byte(`{"items": {"enum": [9007199254740993, 1.5]}, "const": [9007199254740993]}`))
+
+	v, err := s.Validate(strings.NewReader(`[9007199254740992, 9007199254740993, 15e-1, 1.50]`))
+	if err != nil || len(v) != 2 || v[0].Pointer != "/0" || v[1].Pointer != "" {
+		t.Errorf("unexpected %v %v", v, err)
+	}
+}
@@ -0,0 +1,123 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Snapshot serializes the state of the Lexer between two Scan() or
+// Write() invocations, including a partial token of push mode. Together
+// with Restore(), a scan process can be checkpointed and continued later,
+// e.g. after a crash, by seeking the reader to the position reported
+// by Offset().
+func (l *Lexer) Snapshot() []byte {
+	bpos, hold := l.bpos, l.hold
+	if l.burd {
+		// the byte was handed back to the reader, it will be read again
+		bpos, hold = bpos-1, false
+	}
+
+	flags := byte(0)
+	for i, f := range []bool{hold, l.frac, l.expo, l.sign, l.esc, l.burde, l.susp} {
+		if f {
+			flags |= 1 << uint(i)
+		}
+	}
+
+	var part []byte
+	if l.susp {
+		part = l.area
+	}
+
+	snap := make([]byte, 5, 5+4*binary.MaxVarintLen64+len(l.nest)+len(part))
+	snap[0], snap[1], snap[2] = snapshotVersion, flags, l.buff[0]
+	snap[3], snap[4] = byte(l.kind), byte(l.prev)
+	snap = appendUvarint(snap, uint64(bpos))
+	snap = appendUvarint(snap, uint64(l.tpos))
+	snap = appendUvarint(snap, uint64(len(l.nest)))
+	snap = appendUvarint(snap, uint64(len(part)))
+
+	for _, k := range l.nest {
+		snap = append(snap, byte(k))
+	}
+	return append(snap, part...)
+}
+
+// Restore sets the state of the Lexer from a Snapshot(). Snapshots
+// with unknown token kinds or flags are rejected, the Lexer is unchanged.
+func (l *Lexer) Restore(snap []byte) error {
+	if len(snap) < 5 || snap[0] != snapshotVersion {
+		return errInvalidSnapshot
+	}
+	var vals [4]uint64
+	rest := snap[5:]
+	for i := range vals {
+		v, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return errInvalidSnapshot
+		}
+		vals[i], rest = v, rest[n:]
+	}
+	if vals[2] > uint64(len(rest)) || uint64(len(rest))-vals[2] != vals[3] {
+		return errInvalidSnapshot
+	}
+	if !validSnapshot(snap[1], TokenKind(snap[3]), TokenKind(snap[4]), vals, rest[:vals[2]]) {
+		return errInvalidSnapshot
+	}
+
+	flags := snap[1]
+	for i, f := range []*bool{&l.hold, &l.frac, &l.expo, &l.sign, &l.esc, &l.burde, &l.susp} {
+		*f = flags&(1<<uint(i)) != 0
+	}
+	l.buff[0], l.kind, l.prev = snap[2], TokenKind(snap[3]), TokenKind(snap[4])
+	l.bpos, l.tpos = uint(vals[0]), uint(vals[1])
+
+	l.nest = l.nest[:0]
+	for _, k := range rest[:vals[2]] {
+		l.nest = append(l.nest, TokenKind(k))
+	}
+	l.area = append(l.area[:0], rest[vals[2]:]...)
+	l.burd, l.err = false, nil
+
+	return nil
+}
+
+// validSnapshot checks the decoded fields, so that a corrupted
+// snapshot does not restore the Lexer into an undefined state.
+func validSnapshot(flags byte, kind, prev TokenKind, vals [4]uint64, nest []byte) bool {
+	susp := flags&(1<<6) != 0
+	if flags>>7 != 0 || kind >= scanning || prev >= scanning || vals[1] > vals[0] {
+		return false
+	}
+	// a partial token is a string, number or literal
+	if susp && !kind.Is(TokenSTR) && !kind.Is(TokenNUM) && !kind.Is(TokenLIT) || !susp && vals[3] > 0 {
+		return false
+	}
+	for _, k := range nest {
+		if k != byte(TokenLCB) && k != byte(TokenLSB) {
+			return false
+		}
+	}
+	return true
+}
+
+// Offset returns the position in the byte stream where the
+// Lexer will continue to read on the next Scan() invocation.
+func (l *Lexer) Offset() uint {
+	if l.burd {
+		return l.bpos - 1
+	}
+	return l.bpos
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+const snapshotVersion = 2
+
+var errInvalidSnapshot = errors.New("invalid snapshot")
@@ -0,0 +1,88 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func snapshotTokens(t *testing.T, s string, opts ...lexerOpt) []string {
+	var toks []string
+	y := func(kind TokenKind, load []byte, pos uint) bool {
+		toks = append(toks, fmt.Sprintf("%d:%d:%s", pos, kind, load))
+		return true
+	}
+	NewLexer(y, opts...).Scan(bytes.NewReader([]byte(s)))
+	return toks
+}
+
+// checkpoint after each token, continue with a fresh Lexer on a seeked reader
+func TestLexer_Snapshot_1(t *testing.T) {
+	s := ` { "foo": [ 1, -2.5e3 , true,null], "b\"az": 42} `
+
+	for _, opts := range [][]lexerOpt{nil, {LexerOptEnableUnreadBuffer}, {LexerOptEmitKeys, LexerOptEmitNumberKinds}} {
+		e := snapshotTokens(t, s, opts...)
+
+		var (
+			toks []string
+			snap []byte
+		)
+		y := func(kind TokenKind, load []byte, pos uint) bool {
+			toks = append(toks, fmt.Sprintf("%d:%d:%s", pos, kind, load))
+			return false
+		}
+
+		for i := 0; i < len(e); i++ {
+			l := NewLexer(y, opts...)
+			r := bytes.NewReader([]byte(s))
+			if snap != nil {
+				if err := l.Restore(snap); err != nil {
+					t.Fatalf("unexpected %v", err)
+				}
+				if _, err := r.Seek(int64(l.Offset()), io.SeekStart); err != nil {
+					t.Fatalf("unexpected %v", err)
+				}
+			}
+			l.Scan(EnsureAtLeastSingleByteUnreadableReader(r))
+			snap = l.Snapshot()
+		}
+
+		if fmt.Sprint(toks) != fmt.Sprint(e) {
+			t.Errorf("unexpected %v", toks)
+		}
+	}
+}
+
+func TestLexer_Snapshot_2(t *testing.T) {
+	l := NewLexer(func(TokenKind, []byte, uint) bool { return true })
+
+	for _, s := range [][]byte{
+		nil, {9, 0, 0, 0, 0, 0, 0, 0, 0}, {2, 0, 0, 0}, {2, 0, 0, 0, 0, 0, 0},
+		{2, 0, 0, 0, 0, 0, 0, 0, 1}, {2, 0, 0, 0, 0, 0, 0, 1, 0},
+		{2, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{2, 0x80, 0, 0, 0, 0, 0, 0, 0},                                     // unknown flag
+		{2, 0, 0, byte(scanning), 0, 0, 0, 0, 0},                           // unknown kind
+		{2, 0, 0, 0, 0xFF, 0, 0, 0, 0},                                     // unknown previous kind
+		{2, 0, 0, 0, 0, 1, 2, 0, 0},                                        // token behind read position
+		{2, 0, 0, 0, 0, 0, 0, 1, 0, byte(TokenSTR)},                        // invalid nest
+		{2, 1 << 6, 0, byte(TokenLCB), 0, 1, 0, 0, 1, '{'},                 // invalid partial token
+		{2, 0, 0, byte(TokenSTR), 0, 1, 0, 0, 1, 'a'},                      // partial token without suspension
+		{2, 1 << 6, 0, byte(TokenSTR), 0, 2, 0, 1, 1, byte(TokenCOL), 'a'}, // invalid nest with partial token
+	} {
+		if err := l.Restore(s); err != errInvalidSnapshot {
+			t.Errorf("unexpected %v for %v", err, s)
+		}
+	}
+	if err := l.Restore([]byte{2, 1, 'x', 0, 0, 0x80, 0x01, 5, 1, 0, byte(TokenLCB)}); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	if !l.hold || l.buff[0] != 'x' || l.Offset() != 128 || l.tpos != 5 || len(l.nest) != 1 {
+		t.Errorf("unexpected")
+	}
+	if err := l.Restore([]byte{2, 1 << 6, 0, byte(TokenSTR), byte(TokenCOL), 2, 0, 1, 1, byte(TokenLSB), 'a'}); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Tape is a compact index of the tokens of a document: their kinds, byte
+// positions and load sizes. Built once by NewTape(), it allows to jump to
+// any token, to the matching bracket and to the n-th element or member of
+// a container in O(1), without lexing the document again. The loads can be
+// read from the document with Load(), a member lookup by name with Member()
+// is O(n) in the number of members. A Tape can be cached with WriteTo()
+// and loaded again with ReadFrom().
+type Tape struct {
+	kind []TokenKind
+	pos  []uint64
+	size []uint32
+	link []uint32 // matching bracket
+	kids []uint32 // children of containers, grouped by container
+	aux  []uint32 // opening bracket: start in kids, closing bracket: end in kids
+}
+
+// NewTape builds a Tape from the byte stream. The structure of the
+// document is validated, a stream of concatenated values is accepted.
+func NewTape(r io.Reader) (*Tape, error) {
+	var (
+		t    = &Tape{}
+		g    Grammar
+		open []uint32 // indices of open brackets
+		mark []int    // starts of pending children in pend
+		pend []uint32 // pending children of open containers
+		err  error
+	)
+
+	l := NewLexer(func(kind TokenKind, load []byte, pos uint) bool {
+		if kind.Is(TokenERR) {
+			return false
+		}
+		if kind.Is(TokenEOF) {
+			if !g.Done() {
+				err = &SyntaxError{Msg: "unexpected end of input", Pos: pos}
+			}
+			return false
+		}
+		key, ok := g.Push(kind)
+		if !ok {
+			err = &SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+			return false
+		}
+
+		i := uint32(len(t.kind))
+		t.kind = append(t.kind, kind)
+		t.pos = append(t.pos, uint64(pos))
+		t.size = append(t.size, uint32(len(load)))
+		t.link = append(t.link, 0)
+		t.aux = append(t.aux, 0)
+
+		if len(open) > 0 {
+			o := open[len(open)-1]
+			if key || t.kind[o].Is(TokenLSB) && isValueStart(kind) {
+				pend = append(pend, i)
+			}
+		}
+
+		switch kind {
+		case TokenLCB, TokenLSB:
+			open = append(open, i)
+			mark = append(mark, len(pend))
+		case TokenRCB, TokenRSB:
+			o, m := open[len(open)-1], mark[len(mark)-1]
+			open, mark = open[:len(open)-1], mark[:len(mark)-1]
+			t.link[o], t.link[i] = i, o
+			t.aux[o] = uint32(len(t.kids))
+			t.kids = append(t.kids, pend[m:]...)
+			t.aux[i] = uint32(len(t.kids))
+			pend = pend[:m]
+		}
+		return true
+	})
+	l.Scan(r)
+
+	if l.Err() != nil {
+		return nil, l.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Len returns the number of tokens on the Tape.
+func (t *Tape) Len() int {
+	return len(t.kind)
+}
+
+// Kind returns the kind of the i-th token.
+func (t *Tape) Kind(i int) TokenKind {
+	return t.kind[i]
+}
+
+// Pos returns the byte position of the i-th token in the document.
+func (t *Tape) Pos(i int) uint {
+	return uint(t.pos[i])
+}
+
+// Size returns the load size of the i-th token.
+func (t *Tape) Size(i int) int {
+	return int(t.size[i])
+}
+
+// Match returns the index of the bracket matching the i-th token,
+// or -1 when the i-th token is not a bracket.
+func (t *Tape) Match(i int) int {
+	switch t.kind[i] {
+	case TokenLCB, TokenRCB, TokenLSB, TokenRSB:
+		return int(t.link[i])
+	}
+	return -1
+}
+
+// Skip returns the index of the token following the value
+// which starts with the i-th token.
+func (t *Tape) Skip(i int) int {
+	switch t.kind[i] {
+	case TokenLCB, TokenLSB:
+		return int(t.link[i]) + 1
+	}
+	return i + 1
+}
+
+// Children returns the number of elements or members of the
+// container which starts with the i-th token.
+func (t *Tape) Children(i int) int {
+	switch t.kind[i] {
+	case TokenLCB, TokenLSB:
+		return int(t.aux[t.link[i]] - t.aux[i])
+	}
+	return 0
+}
+
+// Child returns the index of the n-th element of an array or the index
+// of the n-th member name of an object, which starts with the i-th token.
+// The member value follows the name and colon at index Child()+2.
+// It returns -1 if there is no such child.
+func (t *Tape) Child(i, n int) int {
+	if n < 0 || n >= t.Children(i) {
+		return -1
+	}
+	return int(t.kids[int(t.aux[i])+n])
+}
+
+// Member returns the index of the value of the member with the given
+// name of the object which starts with the i-th token. The Tape keeps
+// no names: the members are compared one by one, each name at least as
+// long as the given one is read from the document src with one ReadAt().
+// For repeated lookups in large objects, build a map from the names
+// once. It returns -1 if there is no such member.
+func (t *Tape) Member(i int, name string, src io.ReaderAt) (int, error) {
+	var buf, ubuf []byte
+	for n := 0; n < t.Children(i); n++ {
+		k := t.Child(i, n)
+		if t.Size(k) < len(name) {
+			continue
+		}
+		load, err := t.Load(k, src, buf)
+		if err != nil {
+			return -1, err
+		}
+		if buf = load; string(load) == name {
+			return k + 2, nil
+		}
+		if bytes.IndexByte(load, '\\') < 0 {
+			continue
+		}
+		if ubuf, err = Unescape(ubuf[:0], load); err == nil && string(ubuf) == name {
+			return k + 2, nil
+		}
+	}
+	return -1, nil
+}
+
+// Load reads the load of the i-th token from the document src into buf,
+// which is grown if necessary, and returns it.
+func (t *Tape) Load(i int, src io.ReaderAt, buf []byte) ([]byte, error) {
+	n, off := int(t.size[i]), int64(t.pos[i])
+	if t.kind[i].Is(TokenSTR) {
+		off++
+	}
+	if cap(buf) < n {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := src.ReadAt(buf, off); err != nil && !(err == io.EOF && n > 0) {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// WriteTo serializes the Tape.
+func (t *Tape) WriteTo(w io.Writer) (int64, error) {
+	bw := &countingWriter{w: bufio.NewWriter(w)}
+
+	hdr := []uint64{tapeMagic, uint64(len(t.kind)), uint64(len(t.kids))}
+	for _, v := range []interface{}{hdr, t.kind, t.pos, t.size, t.link, t.kids, t.aux} {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return bw.n, err
+		}
+	}
+	return bw.n, bw.w.Flush()
+}
+
+// ReadFrom deserializes a Tape written by WriteTo(). The Tape is
+// validated, a truncated or corrupted input results in an error.
+func (t *Tape) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+
+	var hdr [3]uint64
+	if err := binary.Read(cr, binary.LittleEndian, &hdr); err != nil {
+		return cr.n, err
+	}
+	if hdr[0] != tapeMagic {
+		return cr.n, errInvalidTape
+	}
+	n, k := hdr[1], hdr[2]
+	if n > maxTapeLen || k > n {
+		return cr.n, errInvalidTape
+	}
+
+	var (
+		sect [6][]byte
+		err  error
+	)
+	for i, size := range [6]uint64{1, 8, 4, 4, 4, 4} {
+		m := n
+		if i == 4 {
+			m = k
+		}
+		if sect[i], err = readChunks(cr, m*size); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = errInvalidTape
+			}
+			return cr.n, err
+		}
+	}
+
+	rt := Tape{
+		kind: make([]TokenKind, n), pos: make([]uint64, n), size: make([]uint32, n),
+		link: make([]uint32, n), kids: make([]uint32, k), aux: make([]uint32, n),
+	}
+	for i := range rt.kind {
+		rt.kind[i] = TokenKind(sect[0][i])
+		rt.pos[i] = binary.LittleEndian.Uint64(sect[1][8*i:])
+	}
+	for i, v := range [][]uint32{rt.size, rt.link, rt.kids, rt.aux} {
+		for j := range v {
+			v[j] = binary.LittleEndian.Uint32(sect[i+2][4*j:])
+		}
+	}
+	if !rt.valid() {
+		return cr.n, errInvalidTape
+	}
+	*t = rt
+	return cr.n, nil
+}
+
+// valid checks the kinds, that brackets are paired and nested and
+// that the children of containers are within their brackets, so that
+// the accessors of a deserialized Tape do not run out of range.
+func (t *Tape) valid() bool {
+	var open []uint32
+	for i, kind := range t.kind {
+		switch kind {
+		case TokenLIT, TokenNUM, TokenSTR, TokenCOL, TokenCOM:
+		case TokenLCB, TokenLSB:
+			open = append(open, uint32(i))
+		case TokenRCB, TokenRSB:
+			if len(open) == 0 {
+				return false
+			}
+			o := open[len(open)-1]
+			open = open[:len(open)-1]
+
+			if t.link[o] != uint32(i) || t.link[i] != o || t.kind[o].Is(TokenLCB) != kind.Is(TokenRCB) {
+				return false
+			}
+			if t.aux[o] > t.aux[i] || int(t.aux[i]) > len(t.kids) {
+				return false
+			}
+			for _, c := range t.kids[t.aux[o]:t.aux[i]] {
+				if c <= o || c >= uint32(i) || kind.Is(TokenRCB) && (c+2 >= uint32(i) || !t.kind[c].Is(TokenSTR)) {
+					return false
+				}
+			}
+		default:
+			return false
+		}
+	}
+	return len(open) == 0
+}
+
+// readChunks reads n bytes, growing the buffer while reading,
+// so that a corrupted length fails at the end of the input
+// instead of allocating excessive memory up front.
+func readChunks(r io.Reader, n uint64) ([]byte, error) {
+	var b []byte
+	for n > 0 {
+		k := n
+		if k > 1<<20 {
+			k = 1 << 20
+		}
+		l := len(b)
+		b = append(b, make([]byte, k)...)
+		if _, err := io.ReadFull(r, b[l:]); err != nil {
+			return nil, err
+		}
+		n -= k
+	}
+	return b, nil
+}
+
+func isValueStart(kind TokenKind) bool {
+	switch kind {
+	case TokenSTR, TokenNUM, TokenLIT, TokenLCB, TokenLSB:
+		return true
+	}
+	return false
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+const (
+	tapeMagic  = 0x3165706174786c6a // "jlxtape1"
+	maxTapeLen = 1 << 40
+)
+
+var errInvalidTape = errors.New("invalid tape")
@@ -0,0 +1,141 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const tapeDoc = ` {"a": [1, {"x": null}, "s"], "b\"": true, "c": {} } `
+
+func TestTape_1(t *testing.T) {
+	tp, err := NewTape(strings.NewReader(tapeDoc))
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	if tp.Len() != 24 {
+		t.Errorf("unexpected %d", tp.Len())
+	}
+	if tp.Kind(0) != TokenLCB || tp.Pos(0) != 1 || tp.Match(0) != 23 || tp.Match(23) != 0 {
+		t.Errorf("unexpected %d %d %d", tp.Kind(0), tp.Pos(0), tp.Match(0))
+	}
+	if tp.Skip(0) != 24 || tp.Skip(1) != 2 || tp.Match(1) != -1 {
+		t.Errorf("unexpected %d %d", tp.Skip(0), tp.Skip(1))
+	}
+	if tp.Children(0) != 3 || tp.Children(3) != 3 || tp.Children(21) != 0 {
+		t.Errorf("unexpected %d %d", tp.Children(0), tp.Children(3))
+	}
+	// array elements
+	if tp.Child(3, 0) != 4 || tp.Child(3, 1) != 6 || tp.Child(3, 2) != 12 || tp.Child(3, 3) != -1 {
+		t.Errorf("unexpected %d %d %d", tp.Child(3, 0), tp.Child(3, 1), tp.Child(3, 2))
+	}
+	// member names
+	if tp.Child(0, 0) != 1 || tp.Child(0, 1) != 15 || tp.Child(0, 2) != 19 {
+		t.Errorf("unexpected %d %d %d", tp.Child(0, 0), tp.Child(0, 1), tp.Child(0, 2))
+	}
+}
+
+func TestTape_Member_1(t *testing.T) {
+	src := strings.NewReader(tapeDoc)
+	tp, _ := NewTape(src)
+
+	for name, e := range map[string]int{"a": 3, `b"`: 17, "c": 21, "x": -1} {
+		i, err := tp.Member(0, name, src)
+		if err != nil || i != e {
+			t.Errorf("unexpected %s %d %v", name, i, err)
+		}
+	}
+	i, _ := tp.Member(tp.Child(3, 1), "x", src)
+	if load, _ := tp.Load(i, src, nil); tp.Kind(i) != TokenLIT || string(load) != "null" {
+		t.Errorf("unexpected %d %s", tp.Kind(i), load)
+	}
+}
+
+func TestTape_Load_1(t *testing.T) {
+	src := strings.NewReader(tapeDoc)
+	tp, _ := NewTape(src)
+
+	load, err := tp.Load(15, src, nil)
+	if err != nil || string(load) != `b\"` {
+		t.Errorf("unexpected %s %v", load, err)
+	}
+	load, err = tp.Load(23, src, load)
+	if err != nil || string(load) != "}" {
+		t.Errorf("unexpected %s %v", load, err)
+	}
+}
+
+func TestTape_WriteTo_1(t *testing.T) {
+	tp, _ := NewTape(strings.NewReader(tapeDoc))
+
+	buf := bytes.Buffer{}
+	n, err := tp.WriteTo(&buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("unexpected %d %v", n, err)
+	}
+
+	rt := &Tape{}
+	if m, err := rt.ReadFrom(&buf); err != nil || m != n {
+		t.Fatalf("unexpected %d %v", m, err)
+	}
+	for i := 0; i < tp.Len(); i++ {
+		if rt.Kind(i) != tp.Kind(i) || rt.Pos(i) != tp.Pos(i) || rt.Size(i) != tp.Size(i) ||
+			rt.Match(i) != tp.Match(i) || rt.Children(i) != tp.Children(i) {
+			t.Errorf("unexpected %d", i)
+		}
+	}
+	if rt.Len() != tp.Len() || rt.Child(3, 2) != 12 {
+		t.Errorf("unexpected %d", rt.Len())
+	}
+}
+
+func TestTape_ReadFrom_1(t *testing.T) {
+	if _, err := (&Tape{}).ReadFrom(strings.NewReader("this is not a tape, it is a sentence")); err != errInvalidTape {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestTape_ReadFrom_2(t *testing.T) {
+	tp, _ := NewTape(strings.NewReader(`{"a": [1, {"b": 2}], "c": []}`))
+	buf := bytes.Buffer{}
+	_, _ = tp.WriteTo(&buf)
+	b := buf.Bytes()
+
+	n := tp.Len()
+	link, aux := 24+13*n, 24+17*n+len(tp.kids)*4
+
+	corrupt := func(off int, v ...byte) []byte {
+		c := append([]byte{}, b...)
+		copy(c[off:], v)
+		return c
+	}
+	for i, c := range [][]byte{
+		b[:len(b)-1],                 // truncated
+		corrupt(8, 0, 0, 0, 0, 0, 1), // huge length
+		corrupt(24, byte(TokenRSB)),  // kind
+		corrupt(link, 99),            // link out of range
+		corrupt(link, 3),             // link not paired
+		corrupt(aux, 99),             // aux out of range
+		corrupt(24+17*n, 99),         // kid out of range
+		corrupt(24+17*n, 0),          // kid outside of container
+	} {
+		rt := &Tape{}
+		if _, err := rt.ReadFrom(bytes.NewReader(c)); err != errInvalidTape || rt.Len() != 0 {
+			t.Errorf("unexpected %d %v", i, err)
+		}
+	}
+}
+
+func TestTape_2(t *testing.T) {
+	for _, s := range []string{`{"a" 1}`, `[1,]`, `{"a":1`, `[1 2]`, `[}`, `{"a":}`, `"abc`} {
+		if _, err := NewTape(strings.NewReader(s)); err == nil {
+			t.Errorf("unexpected nil error for %s", s)
+		}
+	}
+	tp, err := NewTape(strings.NewReader(`1 [] "x"`))
+	if err != nil || tp.Len() != 4 {
+		t.Errorf("unexpected %v", err)
+	}
+}
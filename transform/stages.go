@@ -0,0 +1,71 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package transform
+
+import (
+	"bytes"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/dom"
+)
+
+// Drop removes the values, and their member names, at the
+// paths matching the pattern (see Path.Match()).
+func Drop(pattern string) Stage {
+	pt := compile(pattern)
+	return func(tok Token, emit func(Token)) {
+		if !pt.prefix(tok.Path) {
+			emit(tok)
+		}
+	}
+}
+
+// Rename changes the member names at the paths matching the pattern.
+func Rename(pattern, name string) Stage {
+	pt, load := compile(pattern), jsonlex.Escape(nil, name)
+	return func(tok Token, emit func(Token)) {
+		if tok.Kind == jsonlex.TokenKEY && pt.match(tok.Path) {
+			tok.Load = load
+		}
+		emit(tok)
+	}
+}
+
+// Replace substitutes the values at the paths matching the pattern
+// with the given value, which must be valid JSON. The tokens of the
+// value carry the path of the replaced value.
+func Replace(pattern string, value []byte) (Stage, error) {
+	if _, err := dom.Parse(value); err != nil {
+		return nil, err
+	}
+
+	var toks []Token
+	l := jsonlex.NewLexer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		if kind != jsonlex.TokenEOF && kind != jsonlex.TokenCOL && kind != jsonlex.TokenCOM {
+			toks = append(toks, Token{Kind: kind, Load: append([]byte(nil), load...), Pos: pos})
+		}
+		return true
+	}, jsonlex.LexerOptEmitKeys)
+	l.Scan(bytes.NewReader(value))
+
+	pt := compile(pattern)
+	return func(tok Token, emit func(Token)) {
+		if !pt.prefix(tok.Path) {
+			emit(tok)
+			return
+		}
+		if len(tok.Path) > len(pt) {
+			return // inside of replaced value
+		}
+		switch tok.Kind {
+		case jsonlex.TokenKEY:
+			emit(tok)
+		case jsonlex.TokenRCB, jsonlex.TokenRSB:
+		default:
+			for _, t := range toks {
+				t.Path = tok.Path
+				emit(t)
+			}
+		}
+	}, nil
+}
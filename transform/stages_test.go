@@ -0,0 +1,59 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package transform
+
+import (
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+const stagesDoc = `{"users": [{"name": "a", "pw": "x", "meta": {"n": 1}}, {"name": "b", "pw": "y"}], "pw": 0}`
+
+func TestDrop_1(t *testing.T) {
+	e := `{"users":[{"name":"a","meta":{"n":1}},{"name":"b"}],"pw":0}`
+	if r := run(t, stagesDoc, Drop("/users/*/pw")); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+	e = `{"users":[{"name":"b","pw":"y"}],"pw":0}`
+	if r := run(t, stagesDoc, Drop("/users/0")); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestRename_1(t *testing.T) {
+	e := `{"users":[{"login \"x\"":"a","pw":"x","meta":{"n":1}},{"login \"x\"":"b","pw":"y"}],"pw":0}`
+	if r := run(t, stagesDoc, Rename("/users/*/name", `login "x"`)); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestReplace_1(t *testing.T) {
+	s, err := Replace("/users/*/meta", []byte(`{"k": [true]}`))
+	if err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	e := `{"users":[{"name":"a","pw":"x","meta":{"k":[true]}},{"name":"b","pw":"y"}],"pw":0}`
+	if r := run(t, stagesDoc, s); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+	if _, err := Replace("/a", []byte(`{`)); err == nil {
+		t.Errorf("unexpected nil error")
+	}
+}
+
+// chained stages, the last one inserts a member
+func TestChain_1(t *testing.T) {
+	s, _ := Replace("/users/*/pw", []byte(`"***"`))
+	insert := func(tok Token, emit func(Token)) {
+		emit(tok)
+		if tok.Kind == jsonlex.TokenLCB && len(tok.Path) == 0 {
+			emit(Token{Kind: jsonlex.TokenKEY, Load: []byte("v")})
+			emit(Token{Kind: jsonlex.TokenNUM, Load: []byte("2")})
+		}
+	}
+	e := `{"v":2,"users":[{"name":"a","pw":"***"},{"name":"b","pw":"***"}]}`
+	if r := run(t, stagesDoc, Drop("/users/*/meta"), s, Drop("/pw"), insert); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+}
@@ -0,0 +1,185 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+// Package transform rewrites JSON documents while streaming. The tokens
+// of the jsonlex.Lexer pass a chain of stages, which can pass, drop,
+// replace or insert tokens, and end in a Writer. The stages receive the
+// tokens with their path in the document, colons and commas are left
+// out and re-inserted by the Writer.
+package transform
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dtgorski/jsonlex"
+	"github.com/dtgorski/jsonlex/internal/pointer"
+)
+
+type (
+	// Token is a token of the stream with its path in the document.
+	// Member names are of kind jsonlex.TokenKEY, the loads of strings
+	// and member names are not unescaped. There are no jsonlex.TokenCOL
+	// and jsonlex.TokenCOM tokens. Load and Path are only valid during
+	// the invocation of a Stage.
+	Token struct {
+		Kind jsonlex.TokenKind
+		Load []byte
+		Pos  uint
+		Path Path
+	}
+
+	// Path locates a value in the document. Member names share the path
+	// of their values, closing brackets the path of the opening ones.
+	Path []Segment
+
+	// Segment is an object member name or an array index.
+	Segment struct {
+		Key   string // unescaped member name
+		Index int    // array index, -1 for object members
+	}
+
+	// Stage processes a token. It passes the token on by invoking emit,
+	// drops it by not doing so, replaces it by emitting other tokens or
+	// inserts tokens by emitting them additionally.
+	Stage func(tok Token, emit func(Token))
+)
+
+// Run reads the document from r, feeds the tokens through the stages
+// and writes the result to w. A stream of concatenated top level
+// values is accepted, the values are separated by newlines. The
+// structure of the document is validated with jsonlex.Grammar before
+// the tokens reach the stages, the Writer re-inserts colons and commas.
+func Run(r io.Reader, w io.Writer, stages ...Stage) error {
+	wr := NewWriter(w)
+	emit := Chain(func(tok Token) { _ = wr.WriteToken(tok.Kind, tok.Load) }, stages...)
+
+	var (
+		g    jsonlex.Grammar
+		path Path
+		open []jsonlex.TokenKind
+		err  error
+	)
+
+	l := jsonlex.NewLexer(func(kind jsonlex.TokenKind, load []byte, pos uint) bool {
+		switch kind {
+		case jsonlex.TokenEOF:
+			if !g.Done() {
+				err = &jsonlex.SyntaxError{Msg: "unexpected end of input", Pos: pos}
+			}
+			return false
+		case jsonlex.TokenERR:
+			return false
+		}
+		if _, ok := g.Push(kind); !ok {
+			err = &jsonlex.SyntaxError{Msg: fmt.Sprintf("unexpected token %q", load), Pos: pos}
+			return false
+		}
+
+		switch kind {
+		case jsonlex.TokenCOL, jsonlex.TokenCOM:
+			return true
+		case jsonlex.TokenKEY:
+			key, e := jsonlex.Unescape(nil, load)
+			if e != nil {
+				err = &jsonlex.SyntaxError{Msg: e.Error(), Pos: pos}
+				return false
+			}
+			path[len(open)-1] = Segment{Key: string(key), Index: -1}
+			emit(Token{kind, load, pos, path[:len(open)]})
+			return wr.err == nil
+		case jsonlex.TokenRCB, jsonlex.TokenRSB:
+			open, path = open[:len(open)-1], path[:len(path)-1]
+			emit(Token{kind, load, pos, path[:len(open)]})
+			return wr.err == nil
+		}
+
+		// first token of a value
+		if n := len(open); n > 0 && open[n-1] == jsonlex.TokenLSB {
+			path[n-1].Index++
+		}
+		emit(Token{kind, load, pos, path[:len(open)]})
+
+		if kind == jsonlex.TokenLCB || kind == jsonlex.TokenLSB {
+			open, path = append(open, kind), append(path, Segment{Index: -1})
+		}
+		return wr.err == nil
+	}, jsonlex.LexerOptEmitKeys)
+
+	l.Scan(r)
+
+	switch {
+	case l.Err() != nil:
+		return l.Err()
+	case err != nil:
+		return err
+	}
+	return wr.Flush()
+}
+
+// Chain links the stages to a final function, usually writing the tokens,
+// and returns the function to be invoked with the tokens of the stream.
+func Chain(final func(Token), stages ...Stage) func(Token) {
+	next := final
+	for i := len(stages) - 1; i >= 0; i-- {
+		s, n := stages[i], next
+		next = func(tok Token) { s(tok, n) }
+	}
+	return next
+}
+
+// String returns the path as JSON Pointer (RFC 6901).
+func (p Path) String() string {
+	b := strings.Builder{}
+	for _, s := range p {
+		b.WriteByte('/')
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// Match reports whether the path matches the pattern, a JSON Pointer
+// whose segments may be "*" to match any member name or index.
+func (p Path) Match(pattern string) bool {
+	return compile(pattern).match(p)
+}
+
+// String returns the segment as part of a JSON Pointer.
+func (s Segment) String() string {
+	if s.Index >= 0 {
+		return strconv.Itoa(s.Index)
+	}
+	return pointer.Escape(s.Key)
+}
+
+type pattern []string
+
+func compile(p string) pattern {
+	return pointer.Split(p)
+}
+
+// match reports whether the path matches the pattern exactly.
+func (pt pattern) match(p Path) bool {
+	return len(p) == len(pt) && pt.prefix(p)
+}
+
+// prefix reports whether the pattern matches the beginning of the path.
+func (pt pattern) prefix(p Path) bool {
+	if len(p) < len(pt) {
+		return false
+	}
+	for i, s := range pt {
+		if s == "*" {
+			continue
+		}
+		if p[i].Index >= 0 {
+			if s != strconv.Itoa(p[i].Index) {
+				return false
+			}
+		} else if s != p[i].Key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package transform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func run(t *testing.T, s string, stages ...Stage) string {
+	buf := bytes.Buffer{}
+	if err := Run(strings.NewReader(s), &buf, stages...); err != nil {
+		t.Fatalf("unexpected %s", err)
+	}
+	return buf.String()
+}
+
+func TestRun_1(t *testing.T) {
+	s := ` {"a" : [1, {"b": "x\"y"}, []], "c": {}, "d": null} 42 [ ] `
+	if r := run(t, s); r != "{\"a\":[1,{\"b\":\"x\\\"y\"},[]],\"c\":{},\"d\":null}\n42\n[]" {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestRun_2(t *testing.T) {
+	var paths []string
+	collect := func(tok Token, emit func(Token)) {
+		paths = append(paths, tok.Path.String()+"="+string(tok.Load))
+		emit(tok)
+	}
+	run(t, `{"a": [1, {"b/c": 2}], "d": 3}`, collect)
+
+	e := "={ /a=a /a=[ /a/0=1 /a/1={ /a/1/b~1c=b/c /a/1/b~1c=2 /a/1=} /a=] /d=d /d=3 =}"
+	if r := strings.Join(paths, " "); r != e {
+		t.Errorf("unexpected %s", r)
+	}
+}
+
+func TestRun_3(t *testing.T) {
+	for _, s := range []string{
+		`[}`, `{"a": 1`, `]`, `{"\x": 1}`, `@`,
+		`{1:2}`, `{"a" "b"}`, `[1 2]`, `{"a":1,,"b":2}`, `[,]`, `{"a":1}}`,
+	} {
+		if err := Run(strings.NewReader(s), &bytes.Buffer{}); err == nil {
+			t.Errorf("unexpected nil error: %s", s)
+		} else if _, ok := err.(*jsonlex.SyntaxError); !ok {
+			t.Errorf("unexpected %T for %s", err, s)
+		}
+	}
+}
+
+func TestPath_Match_1(t *testing.T) {
+	p := Path{{Key: "a", Index: -1}, {Index: 3}, {Key: "b/c", Index: -1}}
+
+	for pattern, e := range map[string]bool{
+		"/a/3/b~1c": true, "/a/*/b~1c": true, "/*/*/*": true,
+		"/a/3": false, "/a/2/b~1c": false, "/a/3/b/c": false, "": false,
+	} {
+		if p.Match(pattern) != e {
+			t.Errorf("unexpected %s", pattern)
+		}
+	}
+	if !(Path{}).Match("") {
+		t.Errorf("unexpected")
+	}
+}
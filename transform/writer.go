@@ -0,0 +1,108 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package transform
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+// Writer writes a stream of tokens as compact JSON text. Colons and
+// commas are inserted, jsonlex.TokenCOL and jsonlex.TokenCOM are
+// ignored. The loads of strings and member names are written as
+// they are, enclosed in quotation marks.
+type Writer struct {
+	w     *bufio.Writer
+	first []bool // per open container, no element written yet
+	key   bool   // member name written, value pending
+	top   bool   // top level value written
+	err   error
+}
+
+// NewWriter creates a Writer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteToken writes a token. Member names must be of kind
+// jsonlex.TokenKEY. The first error is sticky.
+func (w *Writer) WriteToken(kind jsonlex.TokenKind, load []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	switch kind {
+	case jsonlex.TokenCOL, jsonlex.TokenCOM, jsonlex.TokenEOF, jsonlex.TokenERR:
+		return nil
+	case jsonlex.TokenRCB, jsonlex.TokenRSB:
+		if n := len(w.first); n > 0 {
+			w.first = w.first[:n-1]
+		}
+		w.key = false
+		w.err = w.w.WriteByte(load[0])
+		return w.err
+	}
+
+	w.separate()
+
+	switch kind {
+	case jsonlex.TokenKEY:
+		w.quote(load)
+		w.write(':')
+		w.key = true
+		return w.err
+	case jsonlex.TokenSTR:
+		w.quote(load)
+	default:
+		_, w.err = w.w.Write(load)
+	}
+	w.key = false
+
+	if kind == jsonlex.TokenLCB || kind == jsonlex.TokenLSB {
+		w.first = append(w.first, true)
+	}
+	return w.err
+}
+
+// Flush writes buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.w.Flush()
+	return w.err
+}
+
+// separate writes a comma or newline before a member name or value.
+func (w *Writer) separate() {
+	if w.key {
+		return
+	}
+	if n := len(w.first); n > 0 {
+		if !w.first[n-1] {
+			w.write(',')
+		}
+		w.first[n-1] = false
+		return
+	}
+	if w.top {
+		w.write('\n')
+	}
+	w.top = true
+}
+
+func (w *Writer) quote(load []byte) {
+	w.write('"')
+	if w.err == nil {
+		_, w.err = w.w.Write(load)
+	}
+	w.write('"')
+}
+
+func (w *Writer) write(b byte) {
+	if w.err == nil {
+		w.err = w.w.WriteByte(b)
+	}
+}
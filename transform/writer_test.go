@@ -0,0 +1,52 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dtgorski/jsonlex"
+)
+
+func TestWriter_WriteToken_1(t *testing.T) {
+	buf := bytes.Buffer{}
+	w := NewWriter(&buf)
+
+	for _, tok := range []Token{
+		{Kind: jsonlex.TokenLCB, Load: []byte("{")},
+		{Kind: jsonlex.TokenKEY, Load: []byte("a")},
+		{Kind: jsonlex.TokenLSB, Load: []byte("[")},
+		{Kind: jsonlex.TokenNUM, Load: []byte("1")},
+		{Kind: jsonlex.TokenCOM, Load: []byte(",")},
+		{Kind: jsonlex.TokenLIT, Load: []byte("true")},
+		{Kind: jsonlex.TokenRSB, Load: []byte("]")},
+		{Kind: jsonlex.TokenKEY, Load: []byte("b")},
+		{Kind: jsonlex.TokenSTR, Load: []byte(`\n`)},
+		{Kind: jsonlex.TokenRCB, Load: []byte("}")},
+		{Kind: jsonlex.TokenNUM, Load: []byte("2")},
+	} {
+		if err := w.WriteToken(tok.Kind, tok.Load); err != nil {
+			t.Fatalf("unexpected %s", err)
+		}
+	}
+	if err := w.Flush(); err != nil || buf.String() != "{\"a\":[1,true],\"b\":\"\\n\"}\n2" {
+		t.Errorf("unexpected %s", buf.String())
+	}
+}
+
+type faultyWriter struct{}
+
+func (faultyWriter) Write([]byte) (int, error) {
+	return 0, errors.New("faulty")
+}
+
+func TestWriter_Flush_1(t *testing.T) {
+	w := NewWriter(faultyWriter{})
+	_ = w.WriteToken(jsonlex.TokenNUM, []byte("1"))
+
+	if err := w.Flush(); err == nil || w.WriteToken(jsonlex.TokenNUM, []byte("2")) != err {
+		t.Errorf("unexpected %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Unescape appends the unescaped form of a jsonlex.TokenSTR load to dst
+// and returns the extended buffer. Escaped UTF-16 surrogate pairs are
+// combined, lone surrogates are replaced by the Unicode replacement
+// character U+FFFD.
+func Unescape(dst, load []byte) ([]byte, error) {
+	i := bytes.IndexByte(load, '\\')
+	if i < 0 {
+		return append(dst, load...), nil
+	}
+	dst = append(dst, load[:i]...)
+
+	for i < len(load) {
+		b := load[i]
+		if b != '\\' {
+			dst = append(dst, b)
+			i++
+			continue
+		}
+		if i++; i == len(load) {
+			return dst, errInvalidEscape
+		}
+		switch b = load[i]; b {
+		case '"', '\\', '/':
+			dst = append(dst, b)
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'u':
+			r, ok := hex4(load[i+1:])
+			if !ok {
+				return dst, errInvalidEscape
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if len(load) > i+2 && load[i+1] == '\\' && load[i+2] == 'u' {
+					if lo, ok := hex4(load[i+3:]); ok {
+						r2 = utf16.DecodeRune(r, lo)
+					}
+				}
+				if r2 != utf8.RuneError {
+					i += 6
+				}
+				r = r2
+			}
+			dst = appendRune(dst, r)
+		default:
+			return dst, errInvalidEscape
+		}
+		i++
+	}
+	return dst, nil
+}
+
+// Escape appends s to dst, escaped like a jsonlex.TokenSTR load
+// (not enclosed in quotation marks), and returns the extended buffer.
+// It is the inverse of Unescape().
+func Escape(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			dst = append(dst, '\\', c)
+		case c == '\n':
+			dst = append(dst, '\\', 'n')
+		case c == '\r':
+			dst = append(dst, '\\', 'r')
+		case c == '\t':
+			dst = append(dst, '\\', 't')
+		case c < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c -= 'a' - 10
+		case c >= 'A' && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+func appendRune(dst []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(dst, buf[:n]...)
+}
+
+var errInvalidEscape = errors.New("invalid escape sequence")
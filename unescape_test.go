@@ -0,0 +1,55 @@
+// MIT license · Daniel T. Gorski · dtg [at] lengo [dot] org · 10/2020
+
+package jsonlex
+
+import (
+	"testing"
+)
+
+func TestUnescape_1(t *testing.T) {
+	s := []struct {
+		load string
+		want string
+	}{
+		{load: ``, want: ``},
+		{load: `foo`, want: `foo`},
+		{load: `a\"b`, want: `a"b`},
+		{load: `\\\/\b\f\n\r\t`, want: "\\/\b\f\n\r\t"},
+		{load: `äß`, want: "äß"},
+		{load: `\ud83d\ude00!`, want: "😀!"},
+		{load: `\u00e4\u00DF`, want: "äß"},
+		{load: `\ud83d!`, want: "�!"},
+		{load: `\ude00A`, want: "�A"},
+		{load: `\ud83dA`, want: "�A"},
+		{load: `\ud83d\u0041`, want: "�A"},
+	}
+	for _, v := range s {
+		b, err := Unescape(nil, []byte(v.load))
+		if err != nil || string(b) != v.want {
+			t.Errorf("unexpected %q %v", b, err)
+		}
+	}
+}
+
+func TestUnescape_2(t *testing.T) {
+	s := []string{`\`, `\x`, `\u`, `\u12`, `\u12G4`, `a\`, `\uD800\uZZZZ`, `\ud83d\u12`}
+
+	for _, v := range s {
+		if _, err := Unescape(nil, []byte(v)); err == nil {
+			t.Errorf("unexpected %q", v)
+		}
+	}
+}
+
+func TestUnescape_3(t *testing.T) {
+	b, err := Unescape([]byte("x"), []byte(`y\tz`))
+	if err != nil || string(b) != "xy\tz" {
+		t.Errorf("unexpected %q", b)
+	}
+}
+
+func TestEscape_1(t *testing.T) {
+	if s := string(Escape(nil, "a\"\\\n\x01ä")); s != `a\"\\\n\u0001ä` {
+		t.Errorf("unexpected %s", s)
+	}
+}